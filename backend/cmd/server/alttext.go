@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	altTextMaxRunes    = 500
+	altTextTimeout     = 30 * time.Second
+	altTextConcurrency = 2
+)
+
+var errDescriberDisabled = errors.New("alt text provider is disabled")
+
+// altTextBackfillEnabled สร้าง alt text ให้รูปเก่าตอนย้ายเข้า media หรือไม่ (ปิดไว้ก่อน เพราะเรียก AI ทุกรูปโดยไม่มีใครสั่ง)
+func altTextBackfillEnabled() bool {
+	return os.Getenv("ALT_TEXT_BACKFILL") == "true"
+}
+
+// imageDescription คำอธิบายรูปสำหรับ alt และ caption ทั้งไทยและอังกฤษ
+type imageDescription struct {
+	AltTH     string `json:"altTh"`
+	AltEN     string `json:"altEn"`
+	CaptionTH string `json:"captionTh"`
+	CaptionEN string `json:"captionEn"`
+}
+
+// imageDescriber ผู้ให้บริการสร้างคำอธิบายรูป คืน textGeneration ไว้บันทึก usage (Model ว่าง = ไม่มีค่าใช้จ่าย)
+type imageDescriber interface {
+	Name() string
+	Describe(ctx context.Context, mime string, data []byte) (imageDescription, textGeneration, error)
+}
+
+var describer imageDescriber
+
+// mediaTextStore อ่านรูปและบันทึกผลของ describeMedia (แยกจาก Mongo เพื่อให้เทสด้วย fake provider ได้)
+type mediaTextStore interface {
+	FindMedia(id primitive.ObjectID) (models.Media, error)
+	LoadBlob(id primitive.ObjectID, key string) (string, []byte, bool)
+	// SaveText คืน media หลังบันทึก fillOnly=true เขียนแต่ละช่องเฉพาะเมื่อค่าที่เก็บอยู่ยังว่าง
+	SaveText(m models.Media, set bson.M, fillOnly bool) (models.Media, error)
+	RecordUsage(gen textGeneration, billing usageBilling)
+	// BudgetExceeded งบรายเดือนหมดแล้ว งานที่ไม่มีคนสั่ง (autoDescribeMedia) จะไม่เรียก AI
+	BudgetExceeded() bool
+}
+
+var mediaTexts mediaTextStore = mongoMediaTextStore{}
+
+var (
+	altTextSem = make(chan struct{}, altTextConcurrency)
+	// altTextJobs งาน autoDescribeMedia ที่ยังไม่เสร็จ
+	altTextJobs sync.WaitGroup
+)
+
+// initImageDescriber เลือก provider ด้วย ALT_TEXT_PROVIDER=gemini|fake|off (ค่าเริ่มต้น gemini)
+// รูปเก่าที่ backfillMediaImages ย้ายเข้า media จะสร้าง alt text เฉพาะเมื่อตั้ง ALT_TEXT_BACKFILL=true
+func initImageDescriber() {
+	switch os.Getenv("ALT_TEXT_PROVIDER") {
+	case "off":
+		describer = nil
+	case "fake":
+		describer = fakeDescriber{}
+		log.Println("✅ Alt text ใช้ fake provider")
+	default:
+		describer = geminiDescriber{}
+	}
+}
+
+// geminiDescriber ส่งรูปเข้า Gemini แบบ multimodal แล้วขอผลเป็น JSON
+type geminiDescriber struct{}
+
+func (geminiDescriber) Name() string { return "gemini" }
+
+func (geminiDescriber) Describe(ctx context.Context, mime string, data []byte) (imageDescription, textGeneration, error) {
+	if geminiClient == nil {
+		return imageDescription{}, textGeneration{}, fmt.Errorf("gemini client is nil")
+	}
+	model := geminiClient.GenerativeModel(getTextModel())
+	model.ResponseMIMEType = "application/json"
+
+	prompt := "อธิบายรูปนี้สำหรับผู้ใช้ที่มองไม่เห็นรูป ตอบเป็น JSON object เท่านั้น มีฟิลด์:\n" +
+		"- altTh: alt text ภาษาไทย 1 ประโยค ไม่เกิน 125 ตัวอักษร บอกสิ่งที่เห็นในรูป ไม่ขึ้นต้นด้วย \"รูปภาพของ\"\n" +
+		"- altEn: alt text เดียวกันเป็นภาษาอังกฤษ\n" +
+		"- captionTh: คำบรรยายใต้ภาพภาษาไทยสั้นๆ น่าอ่าน เหมาะกับโพสต์ตกแต่งบ้าน\n" +
+		"- captionEn: คำบรรยายเดียวกันเป็นภาษาอังกฤษ"
+
+	format := strings.TrimPrefix(mime, "image/")
+	resp, err := model.GenerateContent(ctx, genai.ImageData(format, data), genai.Text(prompt))
+	if err != nil {
+		return imageDescription{}, textGeneration{}, err
+	}
+	gen := textGeneration{Model: getTextModel()}
+	if resp.UsageMetadata != nil {
+		gen.PromptTokens = int64(resp.UsageMetadata.PromptTokenCount)
+		gen.CandidateTokens = int64(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return imageDescription{}, gen, fmt.Errorf("empty description candidates")
+	}
+
+	var desc imageDescription
+	raw := strings.TrimSpace(fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]))
+	if err := json.Unmarshal([]byte(raw), &desc); err != nil {
+		return imageDescription{}, gen, fmt.Errorf("description is not a JSON object: %w", err)
+	}
+	return desc, gen, nil
+}
+
+// fakeDescriber คำอธิบายคงที่จากขนาดรูป ใช้ตอนพัฒนา/ทดสอบโดยไม่เรียก AI จริง
+type fakeDescriber struct{}
+
+func (fakeDescriber) Name() string { return "fake" }
+
+func (fakeDescriber) Describe(_ context.Context, mime string, data []byte) (imageDescription, textGeneration, error) {
+	kind := strings.ToUpper(strings.TrimPrefix(mime, "image/"))
+	return imageDescription{
+		AltTH:     fmt.Sprintf("ภาพตัวอย่าง %s ขนาด %d ไบต์", kind, len(data)),
+		AltEN:     fmt.Sprintf("Sample %s image, %d bytes", kind, len(data)),
+		CaptionTH: "ภาพประกอบบทความ",
+		CaptionEN: "Article illustration",
+	}, textGeneration{}, nil
+}
+
+func clipAltText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > altTextMaxRunes {
+		s = string(r[:altTextMaxRunes])
+	}
+	return s
+}
+
+// mongoMediaTextStore อ่าน/เขียน media ใน Mongo
+type mongoMediaTextStore struct{}
+
+func (mongoMediaTextStore) FindMedia(id primitive.ObjectID) (models.Media, error) {
+	var m models.Media
+	err := database.GetCollection("media").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&m)
+	return m, err
+}
+
+func (mongoMediaTextStore) LoadBlob(id primitive.ObjectID, key string) (string, []byte, bool) {
+	return loadMediaBlob(id, key)
+}
+
+// SaveText บันทึก alt/caption ลง media แล้วอัปเดต image_set ที่ฝังอยู่ในโพสต์ที่ใช้รูปนี้
+// fillOnly ใช้ update แบบ pipeline ตรวจค่าปัจจุบันในคำสั่งเดียว ข้อความที่ editor บันทึกระหว่างรอ AI จึงไม่ถูกทับ
+func (mongoMediaTextStore) SaveText(m models.Media, set bson.M, fillOnly bool) (models.Media, error) {
+	var update interface{} = bson.M{"$set": set}
+	if fillOnly {
+		fields := bson.M{}
+		empty := bson.A{}
+		for field, value := range set {
+			if field == "alt_source" {
+				continue
+			}
+			isEmpty := bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + field, ""}}, ""}}
+			// $literal กันข้อความที่ขึ้นต้นด้วย $ ถูกตีความเป็นชื่อฟิลด์
+			fields[field] = bson.M{"$cond": bson.A{isEmpty, bson.M{"$literal": value}, "$" + field}}
+			empty = append(empty, isEmpty)
+		}
+		// alt_source เปลี่ยนเฉพาะเมื่อ AI ได้เติมอย่างน้อยหนึ่งช่อง
+		fields["alt_source"] = bson.M{"$cond": bson.A{bson.M{"$or": empty}, bson.M{"$literal": set["alt_source"]}, "$alt_source"}}
+		update = bson.A{bson.M{"$set": fields}}
+	}
+	var saved models.Media
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := database.GetCollection("media").FindOneAndUpdate(context.TODO(), bson.M{"_id": m.ID}, update, opts).Decode(&saved); err != nil {
+		return m, err
+	}
+	syncPostImageSets(saved)
+	return saved, nil
+}
+
+func (mongoMediaTextStore) RecordUsage(gen textGeneration, billing usageBilling) {
+	recordTextUsage(gen, billing.Profile, billing.User, billing.JobID)
+}
+
+func (mongoMediaTextStore) BudgetExceeded() bool {
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	return budgetExceeded(config)
+}
+
+// describeMedia สร้าง alt/caption ให้ media แล้วอัปเดตโพสต์ที่ใช้รูปนี้ ค่าใช้จ่ายคิดกับ billing
+// overwrite=false จะเติมเฉพาะช่องที่ยังว่าง (ไม่ทับข้อความที่ editor เขียนเอง แม้จะบันทึกระหว่างรอ AI)
+func describeMedia(m models.Media, overwrite bool, billing usageBilling) (models.Media, error) {
+	if describer == nil {
+		return m, errDescriberDisabled
+	}
+	// ใช้รูปย่อที่ใหญ่ที่สุดแทนต้นฉบับ ประหยัด token และรองรับต้นฉบับที่เป็น GIF/WebP
+	key := ""
+	for _, v := range m.Variants {
+		key = v.Key
+	}
+	mime, data, ok := mediaTexts.LoadBlob(m.ID, key)
+	if !ok {
+		return m, errMediaNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), altTextTimeout)
+	defer cancel()
+	desc, gen, err := describer.Describe(ctx, mime, data)
+	if gen.Model != "" {
+		mediaTexts.RecordUsage(gen, billing)
+	}
+	if err != nil {
+		return m, err
+	}
+
+	set := bson.M{}
+	for _, f := range []struct {
+		field string
+		dst   *string
+		value string
+	}{
+		{"alt", &m.Alt, desc.AltTH},
+		{"alt_en", &m.AltEN, desc.AltEN},
+		{"caption", &m.Caption, desc.CaptionTH},
+		{"caption_en", &m.CaptionEN, desc.CaptionEN},
+	} {
+		value := clipAltText(f.value)
+		if value == "" || (*f.dst != "" && !overwrite) {
+			continue
+		}
+		*f.dst = value
+		set[f.field] = value
+	}
+	if len(set) == 0 {
+		return m, nil
+	}
+	m.AltSource = "ai:" + describer.Name()
+	set["alt_source"] = m.AltSource
+	return mediaTexts.SaveText(m, set, !overwrite)
+}
+
+// autoDescribeMedia สร้าง alt/caption ให้รูปใหม่เบื้องหลัง ไม่ให้ request หรืองานสร้างโพสต์ต้องรอ AI
+// ต้องเรียกหลังบันทึกโพสต์ที่ใช้รูปแล้ว เพื่อให้ syncPostImageSets อัปเดต image_set ในโพสต์ได้เมื่อเสร็จ
+// ถ้าสร้างไม่สำเร็จ รูปยังใช้งานได้ตามปกติและสั่งสร้างใหม่ได้ที่ /api/media/:id/describe
+func autoDescribeMedia(id primitive.ObjectID, billing usageBilling) {
+	if describer == nil || id.IsZero() {
+		return
+	}
+	altTextJobs.Add(1)
+	go func() {
+		defer altTextJobs.Done()
+		altTextSem <- struct{}{}
+		defer func() { <-altTextSem }()
+
+		m, err := mediaTexts.FindMedia(id)
+		if err != nil || (m.Alt != "" && m.AltEN != "" && m.Caption != "" && m.CaptionEN != "") {
+			return
+		}
+		if mediaTexts.BudgetExceeded() {
+			log.Printf("⚠️ งบประมาณเดือนนี้หมดแล้ว ข้ามการสร้าง alt text ของ media %s", id.Hex())
+			return
+		}
+		if _, err := describeMedia(m, false, billing); err != nil {
+			log.Printf("⚠️ สร้าง alt text ของ media %s ไม่สำเร็จ: %v", id.Hex(), err)
+		}
+	}()
+}
+
+// autoDescribeImageSet เรียก autoDescribeMedia กับรูปที่ฝังในโพสต์ (nil = รูปไม่ได้อยู่ใน media)
+func autoDescribeImageSet(set *models.ImageSet, billing usageBilling) {
+	if set != nil {
+		autoDescribeMedia(set.MediaID, billing)
+	}
+}
+
+// regenerateMediaAlt สั่งสร้าง alt/caption ใหม่ ?overwrite=true เขียนทับข้อความเดิมทั้งหมด
+func regenerateMediaAlt(c *gin.Context) {
+	m, ok := findMedia(c)
+	if !ok {
+		return
+	}
+	m, err := describeMedia(m, c.Query("overwrite") == "true", usageBilling{Profile: usageProfileEditor, User: requestActor(c)})
+	if err != nil {
+		if errors.Is(err, errDescriberDisabled) {
+			respondError(c, 503, errCodeAIUnavailable, err.Error())
+			return
+		}
+		respondError(c, 500, errCodeAIFailed, "Alt text generation failed: "+err.Error())
+		return
+	}
+	usage, _ := countMediaUsage(m.ID, nil)
+	c.JSON(200, toMediaItem(m, usage))
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryMediaTextStore เก็บ media ในหน่วยความจำแทน Mongo
+type memoryMediaTextStore struct {
+	mu    sync.Mutex
+	media map[primitive.ObjectID]models.Media
+	saved map[primitive.ObjectID]bson.M
+	usage []usageBilling
+	// overBudget ค่าที่ BudgetExceeded คืน
+	overBudget bool
+}
+
+func (s *memoryMediaTextStore) FindMedia(id primitive.ObjectID) (models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.media[id]
+	if !ok {
+		return m, errMediaNotFound
+	}
+	return m, nil
+}
+
+func (s *memoryMediaTextStore) LoadBlob(id primitive.ObjectID, key string) (string, []byte, bool) {
+	if _, err := s.FindMedia(id); err != nil {
+		return "", nil, false
+	}
+	return "image/jpeg", []byte("jpeg:" + key), true
+}
+
+func (s *memoryMediaTextStore) SaveText(m models.Media, set bson.M, fillOnly bool) (models.Media, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !fillOnly {
+		s.media[m.ID] = m
+		s.saved[m.ID] = set
+		return m, nil
+	}
+	// เหมือน pipeline ของ Mongo: เติมเฉพาะช่องที่ค่าที่เก็บอยู่ยังว่าง
+	stored := s.media[m.ID]
+	filled := false
+	for field, dst := range map[string]*string{"alt": &stored.Alt, "alt_en": &stored.AltEN, "caption": &stored.Caption, "caption_en": &stored.CaptionEN} {
+		if v, ok := set[field].(string); ok && *dst == "" {
+			*dst = v
+			filled = true
+		}
+	}
+	if filled {
+		stored.AltSource = set["alt_source"].(string)
+	}
+	s.media[m.ID] = stored
+	s.saved[m.ID] = set
+	return stored, nil
+}
+
+// editMedia จำลอง editor บันทึกข้อความเองระหว่างที่ describeMedia รอ AI
+func (s *memoryMediaTextStore) editMedia(id primitive.ObjectID, edit func(*models.Media)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.media[id]
+	edit(&m)
+	s.media[id] = m
+}
+
+func (s *memoryMediaTextStore) RecordUsage(_ textGeneration, billing usageBilling) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage = append(s.usage, billing)
+}
+
+func (s *memoryMediaTextStore) BudgetExceeded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.overBudget
+}
+
+// editingDescriber เรียก during ระหว่างสร้างคำอธิบาย
+type editingDescriber struct {
+	fakeDescriber
+	during func()
+}
+
+func (d editingDescriber) Describe(ctx context.Context, mime string, data []byte) (imageDescription, textGeneration, error) {
+	d.during()
+	return d.fakeDescriber.Describe(ctx, mime, data)
+}
+
+// billedDescriber fakeDescriber ที่คืน model เพื่อให้มีการบันทึก usage
+type billedDescriber struct{ fakeDescriber }
+
+func (d billedDescriber) Describe(ctx context.Context, mime string, data []byte) (imageDescription, textGeneration, error) {
+	desc, _, err := d.fakeDescriber.Describe(ctx, mime, data)
+	return desc, textGeneration{Model: "test-model"}, err
+}
+
+// useAltTextFakes สลับ describer และ store เป็นของปลอมจนจบเทส
+func useAltTextFakes(t *testing.T, d imageDescriber, media ...models.Media) *memoryMediaTextStore {
+	t.Helper()
+	store := &memoryMediaTextStore{media: map[primitive.ObjectID]models.Media{}, saved: map[primitive.ObjectID]bson.M{}}
+	for _, m := range media {
+		store.media[m.ID] = m
+	}
+	prevDescriber, prevStore := describer, mediaTexts
+	describer, mediaTexts = d, store
+	t.Cleanup(func() { describer, mediaTexts = prevDescriber, prevStore })
+	return store
+}
+
+func TestDescribeMediaFillsOnlyEmptyFields(t *testing.T) {
+	m := models.Media{
+		ID:       primitive.NewObjectID(),
+		Alt:      "ข้อความที่ editor เขียนเอง",
+		Variants: []models.MediaVariant{{Key: "w320.jpg"}, {Key: "w1280.jpg"}},
+	}
+	store := useAltTextFakes(t, fakeDescriber{}, m)
+
+	got, err := describeMedia(m, false, usageBilling{Profile: usageProfileEditor, User: "somchai"})
+	if err != nil {
+		t.Fatalf("describeMedia: %v", err)
+	}
+	if got.Alt != m.Alt {
+		t.Fatalf("Alt = %q, want editor text kept", got.Alt)
+	}
+	// ใช้รูปย่อที่ใหญ่ที่สุด (ตัวสุดท้าย) ส่งให้ provider
+	if !strings.Contains(got.AltEN, "JPEG") || !strings.Contains(got.AltEN, "14 bytes") {
+		t.Fatalf("AltEN = %q, want description of the largest variant", got.AltEN)
+	}
+	if got.AltSource != "ai:fake" {
+		t.Fatalf("AltSource = %q, want ai:fake", got.AltSource)
+	}
+	set := store.saved[m.ID]
+	if _, ok := set["alt"]; ok {
+		t.Fatalf("saved %v, want alt untouched", set)
+	}
+	for _, field := range []string{"alt_en", "caption", "caption_en", "alt_source"} {
+		if _, ok := set[field]; !ok {
+			t.Errorf("saved %v, missing %s", set, field)
+		}
+	}
+
+	got, err = describeMedia(m, true, usageBilling{Profile: usageProfileEditor, User: "somchai"})
+	if err != nil {
+		t.Fatalf("describeMedia overwrite: %v", err)
+	}
+	if got.Alt == m.Alt {
+		t.Fatal("overwrite=true kept the old alt text")
+	}
+	// fake provider ไม่มีค่าใช้จ่ายจึงไม่บันทึก usage
+	if len(store.usage) != 0 {
+		t.Fatalf("usage = %v, want none for the fake provider", store.usage)
+	}
+}
+
+func TestDescribeMediaErrors(t *testing.T) {
+	useAltTextFakes(t, nil)
+	if _, err := describeMedia(models.Media{ID: primitive.NewObjectID()}, false, usageBilling{}); !errors.Is(err, errDescriberDisabled) {
+		t.Fatalf("disabled provider err = %v, want errDescriberDisabled", err)
+	}
+	useAltTextFakes(t, fakeDescriber{})
+	if _, err := describeMedia(models.Media{ID: primitive.NewObjectID()}, false, usageBilling{}); !errors.Is(err, errMediaNotFound) {
+		t.Fatalf("missing blob err = %v, want errMediaNotFound", err)
+	}
+}
+
+func TestAutoDescribeMediaRunsInBackgroundAndBillsCaller(t *testing.T) {
+	fresh := models.Media{ID: primitive.NewObjectID()}
+	done := models.Media{ID: primitive.NewObjectID(), Alt: "a", AltEN: "b", Caption: "c", CaptionEN: "d"}
+	store := useAltTextFakes(t, billedDescriber{}, fresh, done)
+
+	billing := usageBilling{Profile: usageProfileEditor, User: "somchai", JobID: "job-1"}
+	autoDescribeImageSet(&models.ImageSet{MediaID: fresh.ID}, billing)
+	autoDescribeMedia(done.ID, billing)
+	autoDescribeImageSet(nil, billing)
+	altTextJobs.Wait()
+
+	if m := store.media[fresh.ID]; m.Alt == "" || m.CaptionEN == "" {
+		t.Fatalf("fresh media = %+v, want alt and caption filled", m)
+	}
+	if _, ok := store.saved[done.ID]; ok {
+		t.Fatal("media that already has every text was described again")
+	}
+	if len(store.usage) != 1 || store.usage[0] != billing {
+		t.Fatalf("usage = %v, want one record billed to %v", store.usage, billing)
+	}
+}
+
+func TestDescribeMediaKeepsTextSavedDuringCall(t *testing.T) {
+	m := models.Media{ID: primitive.NewObjectID()}
+	var store *memoryMediaTextStore
+	d := editingDescriber{during: func() {
+		store.editMedia(m.ID, func(m *models.Media) { m.Alt = "โซฟาสีเทาข้างหน้าต่าง" })
+	}}
+	store = useAltTextFakes(t, d, m)
+
+	got, err := describeMedia(m, false, usageBilling{})
+	if err != nil {
+		t.Fatalf("describeMedia: %v", err)
+	}
+	if got.Alt != "โซฟาสีเทาข้างหน้าต่าง" || store.media[m.ID].Alt != got.Alt {
+		t.Fatalf("Alt = %q (stored %q), want the text the editor saved during the call", got.Alt, store.media[m.ID].Alt)
+	}
+	if got.AltEN == "" || got.Caption == "" {
+		t.Fatalf("media = %+v, want the other empty fields filled", got)
+	}
+}
+
+func TestAutoDescribeMediaSkipsWhenOverBudget(t *testing.T) {
+	m := models.Media{ID: primitive.NewObjectID()}
+	store := useAltTextFakes(t, billedDescriber{}, m)
+	store.overBudget = true
+
+	autoDescribeMedia(m.ID, usageBilling{Profile: usageProfileAutomation, User: "automation"})
+	altTextJobs.Wait()
+
+	if _, ok := store.saved[m.ID]; ok || len(store.usage) != 0 {
+		t.Fatalf("saved %v, usage %v, want no AI call over budget", store.saved[m.ID], store.usage)
+	}
+}
+
+func TestAltTextBackfillIsOptIn(t *testing.T) {
+	t.Setenv("ALT_TEXT_BACKFILL", "")
+	if altTextBackfillEnabled() {
+		t.Fatal("backfill alt text enabled without ALT_TEXT_BACKFILL")
+	}
+	t.Setenv("ALT_TEXT_BACKFILL", "true")
+	if !altTextBackfillEnabled() {
+		t.Fatal("ALT_TEXT_BACKFILL=true did not enable backfill alt text")
+	}
+}
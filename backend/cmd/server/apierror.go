@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// รหัสข้อผิดพลาดที่ client ใช้ตัดสินใจได้ (ข้อความใน error อาจเปลี่ยนได้)
+const (
+	errCodeInvalidID        = "INVALID_ID"
+	errCodeInvalidBody      = "INVALID_BODY"
+	errCodeValidationFailed = "VALIDATION_FAILED"
+	errCodeNotFound         = "NOT_FOUND"
+	errCodeConflict         = "CONFLICT"
+	errCodeUnauthorized     = "UNAUTHORIZED"
+	errCodeRateLimited      = "RATE_LIMITED"
+	errCodeAIUnavailable    = "AI_UNAVAILABLE"
+	errCodeAIFailed         = "AI_FAILED"
+	errCodePayloadTooLarge  = "PAYLOAD_TOO_LARGE"
+	errCodeUnsupportedMedia = "UNSUPPORTED_MEDIA_TYPE"
+	errCodeInternal         = "INTERNAL"
+)
+
+// fieldError รายละเอียดของฟิลด์ที่ไม่ผ่าน validation
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// respondError รูปแบบ error เดียวกันทุก handler: {"error": "...", "code": "...", "details": [...]}
+// ฟิลด์ error ยังเป็น string เหมือนเดิมเพื่อไม่ให้ client เก่าพัง
+func respondError(c *gin.Context, status int, code, message string, details ...fieldError) {
+	body := gin.H{"error": message, "code": code}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// bindJSON bind + validate body ตาม tag `binding` ถ้าไม่ผ่านจะตอบ 400 และคืน false
+func bindJSON(c *gin.Context, req any) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+
+	if details, ok := validationDetails(err); ok {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", details...)
+		return false
+	}
+	respondError(c, 400, errCodeInvalidBody, "Invalid request body: "+err.Error())
+	return false
+}
+
+// validationDetails แปลง error จาก validator เป็น fieldError คืน false ถ้าไม่ใช่ validation error
+func validationDetails(err error) ([]fieldError, bool) {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil, false
+	}
+	details := make([]fieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		details = append(details, fieldError{
+			Field:   jsonFieldName(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Error(),
+		})
+	}
+	return details, true
+}
+
+// jsonFieldName ชื่อฟิลด์ตาม json tag (ตัดชื่อ struct ด้านหน้าของ namespace ออก)
+func jsonFieldName(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// initValidator ให้ validator รายงานชื่อฟิลด์ตาม json tag แทนชื่อ field ของ Go
+func initValidator() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// paramObjectID อ่าน ObjectID จาก path param ถ้าไม่ถูกต้องจะตอบ 400 และคืน false
+func paramObjectID(c *gin.Context, name string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(name))
+	if err != nil {
+		respondError(c, 400, errCodeInvalidID, "Invalid "+name)
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	bulkActionDelete           = "delete"
+	bulkActionArchive          = "archive"
+	bulkActionPublish          = "publish"
+	bulkActionRetag            = "retag"
+	bulkActionRegenerateImages = "regenerate-images"
+
+	maxBulkItems      = 1000
+	bulkRetagAttempts = 3
+)
+
+var errPostNotFound = errors.New("post not found")
+
+// errPostChanged โพสต์ถูกแก้ไขระหว่างที่ job อ่านแล้วกำลังเขียนกลับ
+var errPostChanged = errors.New("post was modified while the job was running")
+
+// bulkRequest เลือกโพสต์ด้วย ids หรือ filter (ถ้าส่งทั้งคู่จะใช้โพสต์ที่ตรงทั้งสองเงื่อนไข)
+type bulkRequest struct {
+	Action     string         `json:"action" binding:"required,oneof=delete archive publish retag regenerate-images"`
+	IDs        []string       `json:"ids" binding:"max=1000,dive,mongodb"`
+	Filter     *postListQuery `json:"filter"`
+	AddTags    []string       `json:"addTags" binding:"max=10,dive,max=40"`
+	RemoveTags []string       `json:"removeTags" binding:"max=10,dive,max=40"`
+	DryRun     bool           `json:"dryRun"`
+}
+
+// createBulkJob สร้าง bulk job (หรือแค่นับจำนวนเมื่อ dryRun) แล้วทำงานเบื้องหลัง
+func createBulkJob(c *gin.Context) {
+	var req bulkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.IDs) == 0 && req.Filter == nil {
+		respondError(c, 400, errCodeValidationFailed, "ids or filter is required")
+		return
+	}
+	addTags, removeTags := normalizeTags(req.AddTags), normalizeTags(req.RemoveTags)
+	if req.Action == bulkActionRetag && len(addTags) == 0 && len(removeTags) == 0 {
+		respondError(c, 400, errCodeValidationFailed, "retag requires addTags or removeTags")
+		return
+	}
+
+	and := bson.A{bson.M{"deleted_at": bson.M{"$exists": false}}}
+	requested := make([]primitive.ObjectID, 0, len(req.IDs))
+	for _, h := range req.IDs {
+		id, _ := primitive.ObjectIDFromHex(h)
+		requested = append(requested, id)
+	}
+	if len(requested) > 0 {
+		and = append(and, bson.M{"_id": bson.M{"$in": requested}})
+	}
+	if req.Filter != nil {
+		filter, ferr, err := req.Filter.filter()
+		if ferr != nil {
+			ferr.Field = "filter." + ferr.Field
+			respondError(c, 400, errCodeValidationFailed, ferr.Message, *ferr)
+			return
+		}
+		if err != nil {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		and = append(and, filter)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(maxBulkItems + 1).
+		SetProjection(bson.M{"_id": 1})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), bson.M{"$and": and}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if len(rows) > maxBulkItems {
+		respondError(c, 400, errCodeValidationFailed, fmt.Sprintf("More than %d posts match; narrow the filter", maxBulkItems))
+		return
+	}
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	matched := make(map[primitive.ObjectID]bool, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.ID)
+		matched[r.ID] = true
+	}
+	// id ที่ระบุมาแต่ไม่มีอยู่หรืออยู่ในถังขยะ (ถ้าส่ง filter มาด้วย id ที่ไม่ตรง filter ไม่นับเป็น unknown)
+	unknown := make([]primitive.ObjectID, 0)
+	if req.Filter == nil {
+		for _, id := range requested {
+			if !matched[id] {
+				unknown = append(unknown, id)
+			}
+		}
+	}
+
+	if req.DryRun {
+		c.JSON(200, gin.H{"action": req.Action, "dryRun": true, "matched": len(ids), "postIds": ids, "unknownIds": unknown})
+		return
+	}
+	// ระบุ id มาเอง: id ที่ไม่พบจะถูกรายงานเป็นผลล้มเหลวรายตัวแทนที่จะหายไปเงียบๆ
+	if req.Filter == nil {
+		ids = requested
+	}
+	if len(ids) == 0 {
+		respondError(c, 400, errCodeValidationFailed, "No posts match")
+		return
+	}
+
+	job := models.BulkJob{
+		ID:         primitive.NewObjectID(),
+		Action:     req.Action,
+		PostIDs:    ids,
+		AddTags:    addTags,
+		RemoveTags: removeTags,
+		Status:     models.JobStatusQueued,
+		Total:      len(ids),
+		Results:    []models.BulkItemResult{},
+		CreatedBy:  requestActor(c),
+		CreatedAt:  time.Now(),
+	}
+	if _, err := database.GetCollection("bulk_jobs").InsertOne(context.TODO(), job); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	go runBulkJob(job)
+	c.JSON(202, job)
+}
+
+// failInterruptedBulkJobs job ที่ค้างเป็น queued/running ตอนเริ่ม server คือ job ที่หยุดกลางคันเพราะ process ถูกปิด
+// ไม่ทำต่อให้อัตโนมัติ (บางโพสต์อาจทำไปแล้ว) ให้ดูผลรายโพสต์แล้วสั่งใหม่เฉพาะที่ยังไม่เสร็จ
+func failInterruptedBulkJobs() {
+	res, err := database.GetCollection("bulk_jobs").UpdateMany(context.TODO(),
+		bson.M{"status": bson.M{"$in": bson.A{models.JobStatusQueued, models.JobStatusRunning}}},
+		bson.M{"$set": bson.M{
+			"status":      models.JobStatusFailed,
+			"finished_at": time.Now(),
+			"error":       "interrupted by server restart",
+		}})
+	if err != nil {
+		log.Println("⚠️ ตรวจ bulk job ที่ค้างไม่สำเร็จ: ", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("⚠️ bulk job %d รายการหยุดกลางคันจากการรีสตาร์ท ตั้งเป็น failed", res.ModifiedCount)
+	}
+}
+
+// runBulkJob ทำทีละโพสต์ และบันทึกผลลง job ทุกรายการเพื่อให้ดูความคืบหน้าได้
+func runBulkJob(job models.BulkJob) {
+	coll := database.GetCollection("bulk_jobs")
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ bulk job %s ล้มเหลว: %v", job.ID.Hex(), r)
+			_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": job.ID}, bson.M{"$set": bson.M{
+				"status":      models.JobStatusFailed,
+				"finished_at": time.Now(),
+				"error":       fmt.Sprint(r),
+			}})
+		}
+	}()
+	started := time.Now()
+	_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": job.ID}, bson.M{"$set": bson.M{
+		"status":     models.JobStatusRunning,
+		"started_at": started,
+	}})
+
+	for _, id := range job.PostIDs {
+		result := models.BulkItemResult{PostID: id, OK: true}
+		inc := bson.M{"succeeded": 1}
+		if err := applyBulkAction(job, id); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			inc = bson.M{"failed": 1}
+		}
+		_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": job.ID}, bson.M{
+			"$push": bson.M{"results": result},
+			"$inc":  inc,
+		})
+	}
+
+	finished := time.Now()
+	_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": job.ID}, bson.M{"$set": bson.M{
+		"status":      models.JobStatusDone,
+		"finished_at": finished,
+	}})
+	log.Printf("📦 bulk job %s (%s) เสร็จแล้ว %d โพสต์ ใช้เวลา %s", job.ID.Hex(), job.Action, len(job.PostIDs), finished.Sub(started).Round(time.Second))
+}
+
+// updateBulkPost แก้ไขโพสต์หนึ่งรายการพร้อมเพิ่ม version และเก็บ revision
+// filter เป็น versionFilter เมื่อค่าที่เขียนคำนวณจากโพสต์ที่อ่านมา ถ้าไม่ตรงเพราะ version เปลี่ยนจะคืน errPostChanged
+func updateBulkPost(job models.BulkJob, id primitive.ObjectID, filter, set bson.M) error {
+	ensureBaselineRevision(id)
+	coll := database.GetCollection("posts")
+	res, err := coll.UpdateOne(context.TODO(), filter, touchPost(set, job.CreatedBy))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if n, _ := coll.CountDocuments(context.TODO(), activePostFilter(id)); n > 0 {
+			return errPostChanged
+		}
+		return errPostNotFound
+	}
+	_, _ = recordPostRevision(id, job.CreatedBy, "bulk "+job.Action)
+	return nil
+}
+
+func applyBulkAction(job models.BulkJob, id primitive.ObjectID) error {
+	coll := database.GetCollection("posts")
+	switch job.Action {
+	case bulkActionDelete:
+		res, err := coll.UpdateOne(context.TODO(), activePostFilter(id), bson.M{"$set": bson.M{
+			"deleted_at": time.Now(),
+			"deleted_by": job.CreatedBy,
+		}})
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return errPostNotFound
+		}
+		return nil
+
+	case bulkActionArchive:
+		return updateBulkPost(job, id, activePostFilter(id), bson.M{"status": models.PostStatusArchived})
+
+	case bulkActionPublish:
+		if err := updateBulkPost(job, id, activePostFilter(id), bson.M{"status": models.PostStatusPublished}); err != nil {
+			return err
+		}
+		_, _ = coll.UpdateOne(context.TODO(),
+			bson.M{"_id": id, "published_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"published_at": time.Now()}})
+		return nil
+
+	case bulkActionRetag:
+		remove := make(map[string]bool, len(job.RemoveTags))
+		for _, t := range job.RemoveTags {
+			remove[t] = true
+		}
+		// tag ใหม่คำนวณจาก tag เดิม ถ้ามีคนแก้โพสต์ระหว่างนั้นให้อ่านใหม่แล้วคำนวณอีกครั้ง
+		for attempt := 0; attempt < bulkRetagAttempts; attempt++ {
+			var post models.Post
+			if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&post); err != nil {
+				return errPostNotFound
+			}
+			tags := make([]string, 0, len(post.Tags)+len(job.AddTags))
+			for _, t := range post.Tags {
+				if !remove[t] {
+					tags = append(tags, t)
+				}
+			}
+			err := updateBulkPost(job, id, versionFilter(id, post.Version), bson.M{"tags": normalizeTags(append(tags, job.AddTags...))})
+			if err != errPostChanged {
+				return err
+			}
+		}
+		return errPostChanged
+
+	case bulkActionRegenerateImages:
+		var post models.Post
+		if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&post); err != nil {
+			return errPostNotFound
+		}
+		if geminiClient == nil {
+			return errors.New("AI client is not initialized")
+		}
+		var config models.AutoConfig
+		_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+		if budgetExceeded(config) {
+			return errors.New("monthly budget exceeded")
+		}
+		inputs := generationInputs(post)
+		inputs.Source = usageProfileEditor
+		inputs.JobID = job.ID.Hex()
+		started := time.Now()
+		image, err := generateImageOnly(inputs.ImagePrompt)
+		if err != nil {
+			return err
+		}
+		applyImageProvenance(&inputs, time.Since(started), nil)
+		recordImageUsage(usageProfileEditor, job.CreatedBy, inputs.JobID)
+		set := imageFields(watermarkImage(image, usageProfileEditor), mediaSourceGenerated, id)
+		set["generation"] = inputs
+		// ภาพใหม่แทนภาพของโพสต์ version ที่อ่านมา ถ้าโพสต์ถูกแก้หรือลบระหว่างสร้างภาพให้ทิ้ง media ที่เพิ่งเก็บ
+		if err := updateBulkPost(job, id, versionFilter(id, post.Version), set); err != nil {
+			discardStoredImage(set)
+			return err
+		}
+		refreshPostSEO(id)
+		autoDescribeImageSet(storedImageSet(set), usageBilling{Profile: usageProfileEditor, User: job.CreatedBy, JobID: inputs.JobID})
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", job.Action)
+}
+
+// getJobs bulk job ล่าสุด (ไม่รวมผลรายโพสต์)
+func getJobs(c *gin.Context) {
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(50).
+		SetProjection(bson.M{"results": 0, "post_ids": 0})
+	cursor, err := database.GetCollection("bulk_jobs").Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	jobs := make([]models.BulkJob, 0)
+	if err := cursor.All(context.TODO(), &jobs); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, jobs)
+}
+
+func getJob(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var job models.BulkJob
+	if err := database.GetCollection("bulk_jobs").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&job); err != nil {
+		respondError(c, 404, errCodeNotFound, "Job not found")
+		return
+	}
+	c.JSON(200, job)
+}
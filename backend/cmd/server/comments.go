@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	commentMaxRunes       = 2000
+	commentAuthorMaxRunes = 80
+	commentMaxDepth       = 3
+)
+
+// publicComment ความคิดเห็นที่แสดงบนหน้าเว็บ พร้อมคำตอบแบบ thread
+type publicComment struct {
+	ID        string          `json:"id"`
+	Author    string          `json:"author"`
+	Body      string          `json:"body"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Replies   []publicComment `json:"replies"`
+}
+
+func ensureCommentIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("comments").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index ของ comments ได้: ", err)
+	}
+}
+
+// recountComments ตั้ง comment_count ของโพสต์ให้ตรงกับจำนวนความคิดเห็นที่แสดงจริง
+// (อนุมัติแล้วและ parent ทุกชั้นอนุมัติแล้ว เหมือน thread ใน getPublicComments)
+func recountComments(postID primitive.ObjectID) {
+	cursor, err := database.GetCollection("comments").Find(context.TODO(), bson.M{
+		"post_id": postID,
+		"status":  models.CommentStatusApproved,
+	}, options.Find().SetProjection(bson.M{"_id": 1, "parent_id": 1}))
+	if err != nil {
+		log.Println("⚠️ นับความคิดเห็นไม่สำเร็จ: ", err)
+		return
+	}
+	comments := make([]models.Comment, 0)
+	if err := cursor.All(context.TODO(), &comments); err != nil {
+		log.Println("⚠️ นับความคิดเห็นไม่สำเร็จ: ", err)
+		return
+	}
+	n := countCommentTree(buildCommentTree(comments))
+	_, _ = database.GetCollection("posts").UpdateOne(context.TODO(), bson.M{"_id": postID}, bson.M{"$set": bson.M{"comment_count": n}})
+}
+
+// countCommentTree จำนวนความคิดเห็นทั้งหมดใน thread รวมคำตอบทุกชั้น
+func countCommentTree(tree []publicComment) int64 {
+	n := int64(len(tree))
+	for _, cm := range tree {
+		n += countCommentTree(cm.Replies)
+	}
+	return n
+}
+
+// buildCommentTree จัดความคิดเห็น (เรียงตามเวลาแล้ว) เป็น thread
+func buildCommentTree(comments []models.Comment) []publicComment {
+	children := make(map[primitive.ObjectID][]models.Comment)
+	roots := make([]models.Comment, 0)
+	known := make(map[primitive.ObjectID]bool, len(comments))
+	for _, cm := range comments {
+		known[cm.ID] = true
+	}
+	for _, cm := range comments {
+		// คำตอบที่ parent ถูกซ่อน/ลบจะไม่แสดง
+		if cm.ParentID == nil {
+			roots = append(roots, cm)
+		} else if known[*cm.ParentID] {
+			children[*cm.ParentID] = append(children[*cm.ParentID], cm)
+		}
+	}
+
+	var build func(list []models.Comment) []publicComment
+	build = func(list []models.Comment) []publicComment {
+		out := make([]publicComment, 0, len(list))
+		for _, cm := range list {
+			out = append(out, publicComment{
+				ID:        cm.ID.Hex(),
+				Author:    cm.Author,
+				Body:      cm.Body,
+				CreatedAt: cm.CreatedAt,
+				Replies:   build(children[cm.ID]),
+			})
+		}
+		return out
+	}
+	return build(roots)
+}
+
+// getPublicComments ความคิดเห็นที่อนุมัติแล้วของโพสต์
+func getPublicComments(c *gin.Context) {
+	postID, ok := findPublishedPostID(c.Param("slug"))
+	if !ok {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cursor, err := database.GetCollection("comments").Find(context.TODO(), bson.M{
+		"post_id": postID,
+		"status":  models.CommentStatusApproved,
+	}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	comments := make([]models.Comment, 0)
+	_ = cursor.All(context.TODO(), &comments)
+
+	var last time.Time
+	for _, cm := range comments {
+		if cm.CreatedAt.After(last) {
+			last = cm.CreatedAt
+		}
+	}
+	serveCachedJSON(c, buildCommentTree(comments), last)
+}
+
+// submitComment รับความคิดเห็นจากผู้เข้าชม เข้าคิว moderation ก่อนแสดง
+func submitComment(c *gin.Context) {
+	postID, ok := findPublishedPostID(c.Param("slug"))
+	if !ok {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	var req struct {
+		Author   string `json:"author"`
+		Body     string `json:"body"`
+		ParentID string `json:"parentId" binding:"omitempty,mongodb"`
+		// Website เป็น honeypot: ช่องที่ซ่อนจากผู้ใช้ ถ้ามีค่าแปลว่าเป็น bot
+		Website string `json:"website"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if strings.TrimSpace(req.Website) != "" {
+		// ตอบเหมือนสำเร็จเพื่อไม่ให้ bot รู้ตัว
+		c.JSON(202, gin.H{"status": models.CommentStatusPending})
+		return
+	}
+
+	author := strings.TrimSpace(req.Author)
+	body := strings.TrimSpace(req.Body)
+	if author == "" || utf8.RuneCountInString(author) > commentAuthorMaxRunes {
+		respondError(c, 400, errCodeValidationFailed, "author is required (max 80 characters)")
+		return
+	}
+	if body == "" || utf8.RuneCountInString(body) > commentMaxRunes {
+		respondError(c, 400, errCodeValidationFailed, "body is required (max 2000 characters)")
+		return
+	}
+
+	comment := models.Comment{
+		ID:          primitive.NewObjectID(),
+		PostID:      postID,
+		Author:      author,
+		Body:        body,
+		Status:      models.CommentStatusPending,
+		VisitorHash: visitorFingerprint(c),
+		CreatedAt:   time.Now(),
+	}
+
+	coll := database.GetCollection("comments")
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			respondError(c, 400, errCodeInvalidID, "Invalid parentId")
+			return
+		}
+		var parent models.Comment
+		err = coll.FindOne(context.TODO(), bson.M{"_id": parentID, "post_id": postID, "status": models.CommentStatusApproved}).Decode(&parent)
+		if err != nil {
+			respondError(c, 404, errCodeNotFound, "Parent comment not found")
+			return
+		}
+		if parent.Depth+1 >= commentMaxDepth {
+			respondError(c, 400, errCodeValidationFailed, "Reply depth exceeded")
+			return
+		}
+		comment.ParentID = &parentID
+		comment.Depth = parent.Depth + 1
+	}
+
+	if _, err := coll.InsertOne(context.TODO(), comment); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(202, gin.H{"id": comment.ID.Hex(), "status": comment.Status})
+}
+
+// getComments คิว moderation สำหรับ editor (?status=pending|approved|hidden, ?postId=)
+func getComments(c *gin.Context) {
+	status := c.DefaultQuery("status", models.CommentStatusPending)
+	switch status {
+	case models.CommentStatusPending, models.CommentStatusApproved, models.CommentStatusHidden:
+	default:
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{
+			Field: "status", Rule: "oneof", Param: "pending approved hidden",
+			Message: "status must be pending, approved or hidden",
+		})
+		return
+	}
+	filter := bson.M{"status": status}
+	if v := c.Query("postId"); v != "" {
+		postID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			respondError(c, 400, errCodeInvalidID, "Invalid postId")
+			return
+		}
+		filter["post_id"] = postID
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(200)
+	cursor, err := database.GetCollection("comments").Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	comments := make([]models.Comment, 0)
+	if err := cursor.All(context.TODO(), &comments); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, comments)
+}
+
+// moderateComment อนุมัติหรือซ่อนความคิดเห็น
+func moderateComment(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=approve hide"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	status := ""
+	switch req.Action {
+	case "approve":
+		status = models.CommentStatusApproved
+	case "hide":
+		status = models.CommentStatusHidden
+	default:
+		respondError(c, 400, errCodeValidationFailed, "action must be approve or hide")
+		return
+	}
+
+	var comment models.Comment
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := database.GetCollection("comments").FindOneAndUpdate(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":       status,
+		"moderated_by": requestActor(c),
+		"moderated_at": time.Now(),
+	}}, opts).Decode(&comment)
+	if err != nil {
+		respondError(c, 404, errCodeNotFound, "Comment not found")
+		return
+	}
+	recountComments(comment.PostID)
+	c.JSON(200, comment)
+}
+
+// deleteComment ลบความคิดเห็นพร้อมคำตอบทั้งหมดที่อยู่ใต้ความคิดเห็นนั้น
+func deleteComment(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+
+	coll := database.GetCollection("comments")
+	var comment models.Comment
+	if err := coll.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&comment); err != nil {
+		respondError(c, 404, errCodeNotFound, "Comment not found")
+		return
+	}
+
+	ids := []primitive.ObjectID{id}
+	frontier := []primitive.ObjectID{id}
+	for depth := 0; depth < commentMaxDepth && len(frontier) > 0; depth++ {
+		cursor, err := coll.Find(context.TODO(), bson.M{"parent_id": bson.M{"$in": frontier}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			break
+		}
+		var rows []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		_ = cursor.All(context.TODO(), &rows)
+		frontier = frontier[:0]
+		for _, r := range rows {
+			ids = append(ids, r.ID)
+			frontier = append(frontier, r.ID)
+		}
+	}
+
+	res, err := coll.DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	recountComments(comment.PostID)
+	c.JSON(200, gin.H{"status": "deleted", "deleted": res.DeletedCount})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCountCommentTreeSkipsRepliesToHiddenParents(t *testing.T) {
+	root, hidden, reply, orphan, nested := primitive.NewObjectID(), primitive.NewObjectID(),
+		primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	// รายการที่อนุมัติแล้ว: hidden ไม่อยู่ในรายการเพราะถูกซ่อน คำตอบใต้ hidden จึงไม่ควรถูกนับ
+	approved := []models.Comment{
+		{ID: root},
+		{ID: reply, ParentID: &root},
+		{ID: orphan, ParentID: &hidden},
+		{ID: nested, ParentID: &orphan},
+	}
+	if got := countCommentTree(buildCommentTree(approved)); got != 2 {
+		t.Fatalf("count = %d, want 2", got)
+	}
+}
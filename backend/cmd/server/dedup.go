@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	shingleSize               = 4
+	defaultDuplicateThreshold = 0.9
+	defaultDuplicateLookback  = 50
+	maxDuplicateRegenerations = 2
+)
+
+// contentFingerprint คำนวณ SimHash 64 บิตจาก shingle ตัวอักษร (ใช้กับภาษาไทยที่ไม่เว้นวรรคได้)
+// คืนค่าเป็น hex string เพราะ Mongo ไม่รองรับ uint64
+func contentFingerprint(content string) string {
+	runes := make([]rune, 0, len(content))
+	for _, r := range strings.ToLower(content) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r) {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) == 0 {
+		return ""
+	}
+
+	var weights [64]int
+	n := shingleSize
+	if len(runes) < n {
+		n = len(runes)
+	}
+	for i := 0; i+n <= len(runes); i++ {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(string(runes[i : i+n])))
+		sum := h.Sum64()
+		for b := 0; b < 64; b++ {
+			if sum&(1<<uint(b)) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+
+	var fp uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			fp |= 1 << uint(b)
+		}
+	}
+	return fmt.Sprintf("%016x", fp)
+}
+
+// fingerprintSimilarity ความคล้าย 0-1 จาก Hamming distance ของ SimHash สองค่า
+func fingerprintSimilarity(a, b string) float64 {
+	x, errA := strconv.ParseUint(a, 16, 64)
+	y, errB := strconv.ParseUint(b, 16, 64)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return 1 - float64(bits.OnesCount64(x^y))/64
+}
+
+// findNearDuplicate หาโพสต์ล่าสุดที่คล้ายเกิน threshold คืน nil ถ้าไม่พบ
+// exclude คือโพสต์ที่กำลังสร้างเนื้อหาใหม่ (ไม่เทียบกับเนื้อหาเดิมของตัวเอง)
+func findNearDuplicate(fingerprint string, config models.AutoConfig, exclude primitive.ObjectID) (*models.Post, float64) {
+	if fingerprint == "" {
+		return nil, 0
+	}
+
+	threshold := config.DuplicateThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = defaultDuplicateThreshold
+	}
+	lookback := config.DuplicateLookback
+	if lookback <= 0 {
+		lookback = defaultDuplicateLookback
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(int64(lookback)).
+		SetProjection(bson.M{"_id": 1, "fingerprint": 1, "created_at": 1})
+	filter := bson.M{"fingerprint": bson.M{"$exists": true}, "deleted_at": bson.M{"$exists": false}}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, 0
+	}
+
+	recent := make([]models.Post, 0)
+	_ = cursor.All(context.TODO(), &recent)
+	for i := range recent {
+		if sim := fingerprintSimilarity(fingerprint, recent[i].Fingerprint); sim >= threshold {
+			return &recent[i], sim
+		}
+	}
+	return nil, 0
+}
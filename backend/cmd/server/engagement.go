@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ตัวคูณของหน่วยย่อที่พบใน Likes/Comments แบบเดิม เช่น "1.2k", "3 พัน"
+var countSuffixes = map[string]float64{
+	"":      1,
+	"k":     1e3,
+	"m":     1e6,
+	"b":     1e9,
+	"พัน":   1e3,
+	"หมื่น": 1e4,
+	"แสน":   1e5,
+	"ล้าน":  1e6,
+}
+
+var legacyCountPattern = regexp.MustCompile(`^([0-9][0-9,]*(?:\.[0-9]+)?)\s*(k|m|b|พัน|หมื่น|แสน|ล้าน)?`)
+
+// parseLegacyCount แปลงข้อความจำนวนแบบเดิม ("1.2k", "1,234", "2 หมื่น") เป็นตัวเลข
+func parseLegacyCount(s string) (int64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, true
+	}
+	m := legacyCountPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return int64(math.Round(n * countSuffixes[m[2]])), true
+}
+
+// legacyCountValue แปลงค่า likes/comments เดิมทีละฟิลด์ (string, ตัวเลข หรือไม่มีฟิลด์)
+// ใช้ bson.RawValue เพราะโพสต์เดียวกันอาจมีฟิลด์หนึ่งเป็นตัวเลขและอีกฟิลด์เป็น string
+func legacyCountValue(v bson.RawValue) (int64, bool) {
+	switch v.Type {
+	case 0, bson.TypeNull, bson.TypeUndefined:
+		return 0, true
+	case bson.TypeString:
+		return parseLegacyCount(v.StringValue())
+	case bson.TypeInt32, bson.TypeInt64:
+		return v.AsInt64(), true
+	case bson.TypeDouble:
+		return int64(math.Round(v.Double())), true
+	}
+	return 0, false
+}
+
+// migrateLegacyCounts แปลง likes/comments ที่เป็น string ในโพสต์เก่าเป็น like_count/comment_count
+func migrateLegacyCounts() {
+	coll := database.GetCollection("posts")
+	filter := bson.M{"$or": bson.A{
+		bson.M{"likes": bson.M{"$type": "string"}},
+		bson.M{"comments": bson.M{"$type": "string"}},
+	}}
+	cursor, err := coll.Find(context.TODO(), filter, options.Find().SetProjection(bson.M{"likes": 1, "comments": 1}))
+	if err != nil {
+		log.Println("⚠️ Migration likes/comments ล้มเหลว: ", err)
+		return
+	}
+
+	var rows []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Likes    bson.RawValue      `bson:"likes"`
+		Comments bson.RawValue      `bson:"comments"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		log.Println("⚠️ Migration likes/comments ล้มเหลว: ", err)
+		return
+	}
+
+	for _, row := range rows {
+		likes, okL := legacyCountValue(row.Likes)
+		comments, okC := legacyCountValue(row.Comments)
+		if !okL || !okC {
+			log.Printf("⚠️ แปลงจำนวนของโพสต์ %s ไม่ได้ (likes=%v comments=%v) ใช้ 0 แทน", row.ID.Hex(), row.Likes, row.Comments)
+		}
+		_, err := coll.UpdateOne(context.TODO(), bson.M{"_id": row.ID}, bson.M{
+			"$set":   bson.M{"like_count": likes, "comment_count": comments},
+			"$unset": bson.M{"likes": "", "comments": ""},
+		})
+		if err != nil {
+			log.Println("⚠️ Migration likes/comments ล้มเหลว: ", err)
+		}
+	}
+	if len(rows) > 0 {
+		log.Printf("🔢 แปลง likes/comments เป็นตัวเลขแล้ว %d โพสต์", len(rows))
+	}
+}
+
+func ensureEngagementIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("post_likes").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "visitor", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index ของ post_likes ได้: ", err)
+	}
+}
+
+// visitorFingerprint hash ของผู้เข้าชมจาก IP + User-Agent (ไม่เก็บ IP ตรงๆ) ใช้กัน like ซ้ำ
+// ไม่ใช้ id ที่ client ส่งมาเอง เพราะสุ่มใหม่ทุก request เพื่อกดซ้ำได้
+func visitorFingerprint(c *gin.Context) string {
+	h := sha256.New()
+	h.Write([]byte(os.Getenv("ENGAGEMENT_SALT")))
+	h.Write([]byte{0})
+	h.Write([]byte(c.ClientIP()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.UserAgent()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findPublishedPostID หา id ของโพสต์ที่เผยแพร่แล้วจาก slug หรือ id
+func findPublishedPostID(slug string) (primitive.ObjectID, bool) {
+	match := bson.A{bson.M{"slug": slug}}
+	if id, err := primitive.ObjectIDFromHex(slug); err == nil {
+		match = append(match, bson.M{"_id": id})
+	}
+	var row struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	filter := bson.M{"$and": bson.A{publishedFilter(), bson.M{"$or": match}}}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	if err := database.GetCollection("posts").FindOne(context.TODO(), filter, opts).Decode(&row); err != nil {
+		return primitive.NilObjectID, false
+	}
+	return row.ID, true
+}
+
+// likePost กดถูกใจ (ซ้ำได้แต่นับครั้งเดียวต่อผู้เข้าชม)
+func likePost(c *gin.Context) {
+	setLike(c, true)
+}
+
+// unlikePost ยกเลิกถูกใจ
+func unlikePost(c *gin.Context) {
+	setLike(c, false)
+}
+
+func setLike(c *gin.Context, like bool) {
+	id, ok := findPublishedPostID(c.Param("slug"))
+	if !ok {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	visitor := visitorFingerprint(c)
+	likes := database.GetCollection("post_likes")
+	delta := 0
+	if like {
+		_, err := likes.InsertOne(context.TODO(), bson.M{"post_id": id, "visitor": visitor, "created_at": time.Now()})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		if err == nil {
+			delta = 1
+		}
+	} else {
+		res, err := likes.DeleteOne(context.TODO(), bson.M{"post_id": id, "visitor": visitor})
+		if err != nil {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		if res.DeletedCount > 0 {
+			delta = -1
+		}
+	}
+
+	var post models.Post
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := database.GetCollection("posts").FindOneAndUpdate(
+		context.TODO(),
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"like_count": delta}},
+		opts,
+	).Decode(&post)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"likes": post.Likes, "liked": like})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLegacyCountValueMixedTypes(t *testing.T) {
+	raw, err := bson.Marshal(bson.M{"likes": int32(42), "comments": "1.2k", "shares": 3.6, "bad": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row struct {
+		Likes    bson.RawValue `bson:"likes"`
+		Comments bson.RawValue `bson:"comments"`
+		Shares   bson.RawValue `bson:"shares"`
+		Bad      bson.RawValue `bson:"bad"`
+		Missing  bson.RawValue `bson:"missing"`
+	}
+	if err := bson.Unmarshal(raw, &row); err != nil {
+		t.Fatalf("decode mixed row: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		v    bson.RawValue
+		want int64
+		ok   bool
+	}{
+		{"int32", row.Likes, 42, true},
+		{"string", row.Comments, 1200, true},
+		{"double", row.Shares, 4, true},
+		{"bool", row.Bad, 0, false},
+		{"missing", row.Missing, 0, true},
+	} {
+		got, ok := legacyCountValue(tc.v)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%s: legacyCountValue = %d, %v, want %d, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
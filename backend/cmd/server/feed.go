@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	feedItemLimit  = 50
+	feedTitleRunes = 80
+)
+
+// publicBaseURL URL ของ backend (ใช้กับลิงก์ feed/media)
+func publicBaseURL() string {
+	if v := strings.TrimSpace(os.Getenv("PUBLIC_BASE_URL")); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// siteURL URL ของหน้าเว็บ public (ใช้เป็นลิงก์ของแต่ละโพสต์)
+func siteURL() string {
+	if v := strings.TrimSpace(os.Getenv("SITE_URL")); v != "" {
+		return strings.TrimRight(v, "/")
+	}
+	return publicBaseURL()
+}
+
+func siteTitle() string {
+	if v := strings.TrimSpace(os.Getenv("SITE_TITLE")); v != "" {
+		return v
+	}
+	return "KP Group"
+}
+
+func postURL(p models.Post) string {
+	return siteURL() + "/posts/" + url.PathEscape(toPublicPost(p).Slug)
+}
+
+// postTitle ใช้ Title ถ้ามี ไม่งั้นใช้บรรทัดแรกของเนื้อหา ตัดไม่เกิน feedTitleRunes ตัวอักษร
+func postTitle(p models.Post) string {
+	if t := strings.TrimSpace(p.Title); t != "" {
+		return t
+	}
+	line := strings.TrimSpace(p.Content)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	line = strings.Trim(line, "#*_ ")
+	if utf8.RuneCountInString(line) > feedTitleRunes {
+		line = string([]rune(line)[:feedTitleRunes]) + "…"
+	}
+	if line == "" {
+		return p.ID.Hex()
+	}
+	return line
+}
+
+// feedImage URL รูปที่ใช้ใน enclosure: ใช้รูปย่อที่ใหญ่ที่สุดใน media ส่วนรูป data URL จะชี้ไปที่ /media/posts/:id/image
+func feedImage(p models.Post) (string, string, int) {
+	img := strings.TrimSpace(p.Image)
+	if img == "" {
+		return "", "", 0
+	}
+	if set := p.ImageSet; set != nil && len(set.Sources) > 0 {
+		largest := set.Sources[len(set.Sources)-1]
+		return largest.URL, largest.Mime, largest.Size
+	}
+	if mime, data, ok := parseDataURL(img); ok {
+		return fmt.Sprintf("%s/media/posts/%s/image", publicBaseURL(), p.ID.Hex()), mime, len(data)
+	}
+	return img, "image/jpeg", 0
+}
+
+// feedImageText alt และ caption ของรูปโพสต์ (ภาษาไทย) สำหรับใส่ในฟีด
+func feedImageText(p models.Post) (string, string) {
+	if p.ImageSet == nil {
+		return "", ""
+	}
+	return p.ImageSet.Alt, p.ImageSet.Caption
+}
+
+// parseDataURL แยก mime type และข้อมูลจาก data URL แบบ base64
+func parseDataURL(s string) (string, []byte, bool) {
+	if !strings.HasPrefix(s, "data:") {
+		return "", nil, false
+	}
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(s, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", nil, false
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", nil, false
+	}
+	return strings.TrimSuffix(meta, ";base64"), data, true
+}
+
+// loadFeedPosts โพสต์ที่เผยแพร่แล้วล่าสุด กรองด้วย ?topic= หรือ ?profile= (ชื่อผู้โพสต์)
+func loadFeedPosts(c *gin.Context) ([]models.Post, string, error) {
+	filter := bson.M{"$and": bson.A{publishedFilter()}}
+	title := siteTitle()
+	if topic := strings.TrimSpace(c.Query("topic")); topic != "" {
+		filter["$and"] = append(filter["$and"].(bson.A), bson.M{"topic": topic})
+		title += " - " + topic
+	}
+	if profile := strings.TrimSpace(c.Query("profile")); profile != "" {
+		filter["$and"] = append(filter["$and"].(bson.A), bson.M{"user": profile})
+		title += " - " + profile
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(feedItemLimit)
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	posts := make([]models.Post, 0)
+	err = cursor.All(context.TODO(), &posts)
+	return posts, title, err
+}
+
+func feedLastModified(posts []models.Post) time.Time {
+	var last time.Time
+	for _, p := range posts {
+		if t := postLastModified(p); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// selfURL URL ของ feed ปัจจุบันรวม query string
+func selfURL(c *gin.Context) string {
+	u := publicBaseURL() + c.Request.URL.Path
+	if q := c.Request.URL.RawQuery; q != "" {
+		u += "?" + q
+	}
+	return u
+}
+
+// --- RSS 2.0 ---
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Media   string     `xml:"xmlns:media,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description"`
+	Author      string        `xml:"author,omitempty"`
+	PubDate     string        `xml:"pubDate"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+	Media       *rssMedia     `xml:"media:content,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+// rssMedia รูปตาม Media RSS ใช้ส่ง alt (description) และ caption (title) ไปกับรูป
+type rssMedia struct {
+	URL         string `xml:"url,attr"`
+	Type        string `xml:"type,attr"`
+	Medium      string `xml:"medium,attr"`
+	Width       int    `xml:"width,attr,omitempty"`
+	Height      int    `xml:"height,attr,omitempty"`
+	Title       string `xml:"media:title,omitempty"`
+	Description string `xml:"media:description,omitempty"`
+}
+
+func getRSSFeed(c *gin.Context) {
+	posts, title, err := loadFeedPosts(c)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	last := feedLastModified(posts)
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Media:   "http://search.yahoo.com/mrss/",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        siteURL(),
+			Description: title,
+			Language:    "th",
+			AtomLink:    rssLink{Href: selfURL(c), Rel: "self", Type: "application/rss+xml"},
+			Items:       make([]rssItem, 0, len(posts)),
+		},
+	}
+	if !last.IsZero() {
+		feed.Channel.LastBuildDate = last.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, p := range posts {
+		item := rssItem{
+			Title:       postTitle(p),
+			Link:        postURL(p),
+			GUID:        rssGUID{Value: p.ID.Hex()},
+			Description: p.Content,
+			PubDate:     postPublishedAt(p).UTC().Format(time.RFC1123Z),
+		}
+		if img, mime, size := feedImage(p); img != "" {
+			item.Enclosure = &rssEnclosure{URL: img, Type: mime, Length: size}
+			alt, caption := feedImageText(p)
+			item.Media = &rssMedia{URL: img, Type: mime, Medium: "image", Title: caption, Description: alt}
+			if set := p.ImageSet; set != nil && len(set.Sources) > 0 {
+				largest := set.Sources[len(set.Sources)-1]
+				item.Media.Width, item.Media.Height = largest.Width, largest.Height
+			}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	serveXML(c, "application/rss+xml; charset=utf-8", feed, last)
+}
+
+// --- Atom ---
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Lang    string      `xml:"xml:lang,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int    `xml:"length,attr,omitempty"`
+	Title  string `xml:"title,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    atomAuthor  `xml:"author"`
+	Links     []atomLink  `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func getAtomFeed(c *gin.Context) {
+	posts, title, err := loadFeedPosts(c)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	last := feedLastModified(posts)
+	if last.IsZero() {
+		last = time.Now()
+	}
+	feed := atomFeed{
+		Lang:    "th",
+		ID:      selfURL(c),
+		Title:   title,
+		Updated: last.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: selfURL(c), Rel: "self", Type: "application/atom+xml"},
+			{Href: siteURL(), Rel: "alternate", Type: "text/html"},
+		},
+		Entries: make([]atomEntry, 0, len(posts)),
+	}
+
+	for _, p := range posts {
+		entry := atomEntry{
+			ID:        "urn:post:" + p.ID.Hex(),
+			Title:     postTitle(p),
+			Updated:   postLastModified(p).UTC().Format(time.RFC3339),
+			Published: postPublishedAt(p).UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: p.User},
+			Links:     []atomLink{{Href: postURL(p), Rel: "alternate", Type: "text/html"}},
+			Content:   atomContent{Type: "text", Value: p.Content},
+		}
+		if img, mime, size := feedImage(p); img != "" {
+			alt, _ := feedImageText(p)
+			entry.Links = append(entry.Links, atomLink{Href: img, Rel: "enclosure", Type: mime, Length: size, Title: alt})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	serveXML(c, "application/atom+xml; charset=utf-8", feed, last)
+}
+
+func serveXML(c *gin.Context, contentType string, v any, lastModified time.Time) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	serveCached(c, contentType, append([]byte(xml.Header), body...), lastModified)
+}
+
+// --- JSON Feed 1.1 ---
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Language    string         `json:"language"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentText   string               `json:"content_text"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []jsonFeedAuthor     `json:"authors"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int    `json:"size_in_bytes,omitempty"`
+	Title       string `json:"title,omitempty"`
+}
+
+func getJSONFeed(c *gin.Context) {
+	posts, title, err := loadFeedPosts(c)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       title,
+		HomePageURL: siteURL(),
+		FeedURL:     selfURL(c),
+		Language:    "th",
+		Items:       make([]jsonFeedItem, 0, len(posts)),
+	}
+	for _, p := range posts {
+		item := jsonFeedItem{
+			ID:            p.ID.Hex(),
+			URL:           postURL(p),
+			Title:         postTitle(p),
+			ContentText:   p.Content,
+			DatePublished: postPublishedAt(p).UTC().Format(time.RFC3339),
+			DateModified:  postLastModified(p).UTC().Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: p.User}},
+		}
+		if img, mime, size := feedImage(p); img != "" {
+			item.Image = img
+			alt, _ := feedImageText(p)
+			item.Attachments = []jsonFeedAttachment{{URL: img, MimeType: mime, SizeInBytes: size, Title: alt}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	serveCached(c, "application/feed+json; charset=utf-8", body, feedLastModified(posts))
+}
+
+// getPostImage ส่งรูปที่เก็บเป็น data URL ในโพสต์ออกเป็นไฟล์รูป
+func getPostImage(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+
+	var post models.Post
+	filter := bson.M{"$and": bson.A{publishedFilter(), bson.M{"_id": id}}}
+	if err := database.GetCollection("posts").FindOne(context.TODO(), filter).Decode(&post); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	mime, data, ok := parseDataURL(post.Image)
+	if !ok {
+		if strings.HasPrefix(post.Image, "http") {
+			c.Redirect(302, post.Image)
+			return
+		}
+		respondError(c, 404, errCodeNotFound, "Image not found")
+		return
+	}
+	serveCached(c, mime, data, postLastModified(post))
+}
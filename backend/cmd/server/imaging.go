@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math"
+	"net/http"
+	"strings"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	imageJPEGQuality  = 82
+	blurhashSampleW   = 32
+	blurhashXComps    = 4
+	blurhashYComps    = 3
+	maxImagePixels    = 40_000_000
+	maxImageDimension = 10_000
+)
+
+// ความกว้างของรูปย่อสำหรับ srcset (ไม่ขยายรูปที่เล็กกว่านี้)
+var imageVariantWidths = []int{320, 640, 1280}
+
+var errUnsupportedImage = errors.New("unsupported image format")
+
+// encodedImage ผลลัพธ์หนึ่งไฟล์จาก pipeline
+type encodedImage struct {
+	Key    string
+	Mime   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// processedImage รูปต้นฉบับพร้อมรูปย่อและ blurhash
+type processedImage struct {
+	Original encodedImage
+	Variants []encodedImage
+	Blurhash string
+}
+
+// sniffImageMime ตรวจ mime จาก byte จริง ไม่เชื่อ mime ที่ client ส่งมา
+func sniffImageMime(data []byte) string {
+	mime := http.DetectContentType(data)
+	if _, ok := imageExtensions[mime]; ok {
+		return mime
+	}
+	return ""
+}
+
+// processImage ย่อรูปเป็นหลายขนาด (JPEG) และคำนวณ blurhash
+// ไม่มี WebP encoder ที่เป็น pure Go จึงเข้ารหัสรูปย่อเป็น JPEG ทั้งหมด
+func processImage(data []byte) (processedImage, error) {
+	mime := sniffImageMime(data)
+	if mime == "" {
+		return processedImage{}, errUnsupportedImage
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return processedImage{}, fmt.Errorf("%w: %v", errUnsupportedImage, err)
+	}
+	if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension || cfg.Width*cfg.Height > maxImagePixels {
+		return processedImage{}, fmt.Errorf("image is too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return processedImage{}, fmt.Errorf("%w: %v", errUnsupportedImage, err)
+	}
+
+	b := src.Bounds()
+	out := processedImage{Original: encodedImage{
+		Key:    "original" + imageExtensions[mime],
+		Mime:   mime,
+		Width:  b.Dx(),
+		Height: b.Dy(),
+		Data:   data,
+	}}
+
+	widths := make([]int, 0, len(imageVariantWidths))
+	for _, w := range imageVariantWidths {
+		if w < b.Dx() {
+			widths = append(widths, w)
+		}
+	}
+	// รูปที่ไม่ใหญ่กว่าขนาดย่อสูงสุดได้ JPEG ขนาดเดิมเพิ่มอีกหนึ่งไฟล์
+	if b.Dx() <= imageVariantWidths[len(imageVariantWidths)-1] {
+		widths = append(widths, b.Dx())
+	}
+	for _, w := range widths {
+		resized := resizeImage(src, w)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, flattenImage(resized), &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+			return processedImage{}, err
+		}
+		out.Variants = append(out.Variants, encodedImage{
+			Key:    fmt.Sprintf("w%d.jpg", w),
+			Mime:   "image/jpeg",
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+			Data:   buf.Bytes(),
+		})
+	}
+
+	out.Blurhash = encodeBlurhash(flattenImage(resizeImage(src, blurhashSampleW)), blurhashXComps, blurhashYComps)
+	return out, nil
+}
+
+// resizeImage ย่อรูปให้กว้าง width โดยคงสัดส่วน
+func resizeImage(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	if width >= b.Dx() {
+		return src
+	}
+	height := int(math.Round(float64(b.Dy()) * float64(width) / float64(b.Dx())))
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// flattenImage วางรูปบนพื้นขาว เพราะ JPEG ไม่มี alpha (พื้นโปร่งใสจะกลายเป็นสีดำ)
+func flattenImage(src image.Image) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	stddraw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, stddraw.Src)
+	stddraw.Draw(dst, dst.Bounds(), src, b.Min, stddraw.Over)
+	return dst
+}
+
+// --- blurhash (https://blurha.sh) ---
+
+const blurhashChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// encodeBlurhash สร้าง placeholder สั้นๆ ให้ frontend แสดงระหว่างโหลดรูป
+func encodeBlurhash(img image.Image, xComps, yComps int) string {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return ""
+	}
+
+	// แปลงเป็น linear RGB ครั้งเดียว
+	linear := make([][3]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			linear[y*w+x] = [3]float64{srgbToLinear(c.R), srgbToLinear(c.G), srgbToLinear(c.B)}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComps*yComps)
+	for j := 0; j < yComps; j++ {
+		for i := 0; i < xComps; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+			var f [3]float64
+			for y := 0; y < h; y++ {
+				for x := 0; x < w; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+					px := linear[y*w+x]
+					f[0] += basis * px[0]
+					f[1] += basis * px[1]
+					f[2] += basis * px[2]
+				}
+			}
+			scale := norm / float64(w*h)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(encodeBase83((xComps-1)+(yComps-1)*9, 1))
+
+	maxValue := 1.0
+	if len(factors) > 1 {
+		actualMax := 0.0
+		for _, f := range factors[1:] {
+			for _, v := range f {
+				actualMax = math.Max(actualMax, math.Abs(v))
+			}
+		}
+		quantised := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantised+1) / 166
+		sb.WriteString(encodeBase83(quantised, 1))
+	} else {
+		sb.WriteString(encodeBase83(0, 1))
+	}
+
+	dc := factors[0]
+	sb.WriteString(encodeBase83(linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4))
+	for _, f := range factors[1:] {
+		q := func(v float64) int {
+			return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		sb.WriteString(encodeBase83(q(f[0])*19*19+q(f[1])*19+q(f[2]), 2))
+	}
+	return sb.String()
+}
+
+func encodeBase83(value, length int) string {
+	out := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		out[i-1] = blurhashChars[digit]
+	}
+	return string(out)
+}
+
+func srgbToLinear(v uint8) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = math.Max(0, math.Min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// gradientPNG รูป PNG ขนาด w×h สีไล่ระดับ (ไม่ใช่สีเดียว จะได้เห็นว่าย่อถูกทิศ)
+func gradientPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessImageVariants(t *testing.T) {
+	type size struct{ w, h int }
+	tests := []struct {
+		name string
+		w, h int
+		want []size
+	}{
+		// รูปใหญ่ได้ครบทุกขนาด ไม่มีไฟล์ขนาดเดิมเพิ่ม
+		{"large", 2000, 1000, []size{{320, 160}, {640, 320}, {1280, 640}}},
+		// ไม่ขยายรูปเล็ก: ได้เฉพาะขนาดที่เล็กกว่า และ JPEG ขนาดเดิม
+		{"small", 500, 300, []size{{320, 192}, {500, 300}}},
+		{"exact", 1280, 720, []size{{320, 180}, {640, 360}, {1280, 720}}},
+		{"tiny", 100, 400, []size{{100, 400}}},
+		{"portrait", 900, 1600, []size{{320, 569}, {640, 1138}, {900, 1600}}},
+	}
+	for _, tt := range tests {
+		out, err := processImage(gradientPNG(t, tt.w, tt.h))
+		if err != nil {
+			t.Fatalf("%s: processImage: %v", tt.name, err)
+		}
+		if out.Original.Mime != "image/png" || out.Original.Width != tt.w || out.Original.Height != tt.h {
+			t.Errorf("%s: original = %s %dx%d, want image/png %dx%d", tt.name, out.Original.Mime, out.Original.Width, out.Original.Height, tt.w, tt.h)
+		}
+		if len(out.Variants) != len(tt.want) {
+			t.Fatalf("%s: got %d variants, want %d", tt.name, len(out.Variants), len(tt.want))
+		}
+		for i, v := range out.Variants {
+			want := tt.want[i]
+			if v.Width != want.w || v.Height != want.h || v.Mime != "image/jpeg" {
+				t.Errorf("%s: variant %d = %s %dx%d, want image/jpeg %dx%d", tt.name, i, v.Mime, v.Width, v.Height, want.w, want.h)
+			}
+			// ขนาดที่บันทึกต้องตรงกับ JPEG ที่เข้ารหัสจริง
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(v.Data))
+			if err != nil {
+				t.Fatalf("%s: variant %d is not a JPEG: %v", tt.name, i, err)
+			}
+			if cfg.Width != v.Width || cfg.Height != v.Height {
+				t.Errorf("%s: variant %d encoded as %dx%d, recorded %dx%d", tt.name, i, cfg.Width, cfg.Height, v.Width, v.Height)
+			}
+		}
+		if out.Blurhash == "" {
+			t.Errorf("%s: empty blurhash", tt.name)
+		}
+	}
+}
+
+func TestProcessImageRejects(t *testing.T) {
+	if _, err := processImage([]byte("not an image")); !errors.Is(err, errUnsupportedImage) {
+		t.Fatalf("processImage(text) err = %v, want errUnsupportedImage", err)
+	}
+}
+
+func TestEncodeBlurhash(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 8), G: uint8(y * 10), B: uint8(255 - (x+y)*4), A: 255})
+		}
+	}
+	// ค่าอ้างอิงจาก github.com/buckket/go-blurhash (port ของ reference implementation) กับรูปเดียวกัน
+	const want = "LxH27@2xw#X9mMWZjuf9gMfkfQfk"
+	if got := encodeBlurhash(img, 4, 3); got != want {
+		t.Fatalf("encodeBlurhash = %q, want %q", got, want)
+	}
+	if got := encodeBlurhash(image.NewNRGBA(image.Rect(0, 0, 0, 0)), 4, 3); got != "" {
+		t.Fatalf("encodeBlurhash(empty) = %q, want empty", got)
+	}
+}
@@ -95,8 +95,25 @@ func main() {
 		dbName = "kpgroup_db"
 	}
 	database.Connect(uri, dbName)
+	ensureEngagementIndexes()
+	ensureCommentIndexes()
+	ensureRevisionIndexes()
+	ensureSearchIndex()
+	ensurePostSlugIndex()
+	ensureTaxonomyIndexes()
+	ensureMediaIndexes()
+	migrateLegacyCounts()
+	unsetStoredTime()
+	failInterruptedBulkJobs()
+	go backfillSEO()
+	go backfillSearchIndex()
+	go runTrashPurger()
+	go backfillMediaImages()
 
 	initAI()
+	initRateLimiter()
+	initImageDescriber()
+	initValidator()
 
 	scheduler = cron.New()
 	scheduler.Start()
@@ -113,7 +130,8 @@ func main() {
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-User, X-API-Key, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Retry-After, ETag")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -121,17 +139,76 @@ func main() {
 		c.Next()
 	})
 
-	api := r.Group("/api")
+	// Public API เปิดให้ภายนอกเรียกได้ (เขียนได้เฉพาะ like/comment)
+	public := r.Group("/public/v1")
+	{
+		public.GET("/posts", getPublicPosts)
+		public.GET("/posts/:slug", getPublicPost)
+		public.GET("/tags", getPublicTags)
+		public.POST("/posts/:slug/like", rateLimitMiddleware("engagement"), likePost)
+		public.DELETE("/posts/:slug/like", rateLimitMiddleware("engagement"), unlikePost)
+		public.GET("/posts/:slug/comments", getPublicComments)
+		public.POST("/posts/:slug/comments", rateLimitMiddleware("comment"), submitComment)
+		public.POST("/posts/:slug/variants/:key/track", rateLimitMiddleware("engagement"), trackVariant)
+	}
+
+	// Feeds สำหรับ syndication (?topic= / ?profile=)
+	r.GET("/feed.xml", getRSSFeed)
+	r.GET("/atom.xml", getAtomFeed)
+	r.GET("/feed.json", getJSONFeed)
+	r.GET("/media/posts/:id/image", getPostImage)
+	r.GET("/media/:id/:file", getMediaFile)
+
+	// SEO
+	r.GET("/sitemap.xml", getSitemap)
+	r.GET("/sitemaps/:file", getSitemapPage)
+	r.GET("/robots.txt", getRobots)
+
+	api := r.Group("/api", adminAuthMiddleware())
 	{
 		api.GET("/posts", getPosts)
+		api.GET("/posts/search", searchPosts)
+		api.GET("/posts/:id", getPost)
 		api.POST("/posts", createPost)
 		api.PUT("/posts/:id", updatePost)
 		api.DELETE("/posts/:id", deletePost)
-		api.POST("/generate-content", handleGenerateContent)
-		api.POST("/generate-image", handleGenerateImage)
+		api.GET("/posts/trash", getTrash)
+		api.POST("/posts/bulk", rateLimitMiddleware("trigger"), createBulkJob)
+		api.GET("/jobs", getJobs)
+		api.GET("/export", exportPosts)
+		api.POST("/import", importPosts)
+		api.GET("/jobs/:id", getJob)
+		api.POST("/posts/:id/restore", restorePost)
+		api.POST("/posts/:id/regenerate", rateLimitMiddleware("trigger"), regeneratePost)
+		api.GET("/tags", getTags)
+		api.GET("/media", getMediaLibrary)
+		api.POST("/media", uploadMedia)
+		api.GET("/media/:id", getMedia)
+		api.PUT("/media/:id", updateMedia)
+		api.DELETE("/media/:id", deleteMedia)
+		api.POST("/media/:id/describe", rateLimitMiddleware("text"), regenerateMediaAlt)
+		api.GET("/categories", getCategories)
+		api.POST("/categories", createCategory)
+		api.PUT("/categories/:id", updateCategory)
+		api.DELETE("/categories/:id", deleteCategory)
+		api.GET("/posts/:id/revisions", getRevisions)
+		api.GET("/posts/:id/revisions/diff", getRevisionDiff)
+		api.GET("/posts/:id/revisions/:rev", getRevision)
+		api.POST("/posts/:id/revisions/:rev/restore", restoreRevision)
+		api.PUT("/posts/:id/variants", saveVariants)
+		api.POST("/posts/:id/variants/:key/winner", pickVariantWinner)
+		api.GET("/moderation/queue", getModerationQueue)
+		api.POST("/posts/:id/review", reviewPost)
+		api.GET("/comments", getComments)
+		api.PUT("/comments/:id", moderateComment)
+		api.DELETE("/comments/:id", deleteComment)
+		api.POST("/generate-content", rateLimitMiddleware("text"), handleGenerateContent)
+		api.POST("/generate-image", rateLimitMiddleware("image"), handleGenerateImage)
 		api.GET("/auto-config", getAutoConfig)
 		api.POST("/auto-config", saveAutoConfig)
-		api.POST("/generate-now", manualTriggerAI)
+		api.POST("/generate-now", rateLimitMiddleware("trigger"), manualTriggerAI)
+		api.GET("/usage", getUsage)
+		api.GET("/generation/stats", getGenerationStats)
 	}
 
 	port := os.Getenv("PORT")
@@ -204,16 +281,25 @@ type aiPostJSON struct {
 	ImagePrompt string `json:"image_prompt"`
 }
 
-// Gemini สร้าง “content” อย่างเดียว
-func generateContentOnly(topic, basePrompt string, wordLimit int) (string, error) {
+// textGeneration ผลลัพธ์จาก Gemini พร้อมข้อมูลประกอบสำหรับ moderation และ usage
+type textGeneration struct {
+	Content       string
+	Model         string
+	FinishReason  string
+	SafetyRatings []models.SafetyRating
+
+	PromptTokens    int64
+	CandidateTokens int64
+	Latency         time.Duration
+}
+
+// Gemini สร้าง “content” อย่างเดียว คืน finish reason, safety ratings และ token usage มาด้วย
+func generateText(topic, basePrompt string, wordLimit int) (textGeneration, error) {
 	if geminiClient == nil {
-		return "", fmt.Errorf("gemini client is nil")
+		return textGeneration{}, fmt.Errorf("gemini client is nil")
 	}
 
-	textModelName := os.Getenv("GEMINI_MODEL")
-	if textModelName == "" {
-		textModelName = "gemini-2.5-flash"
-	}
+	textModelName := getTextModel()
 
 	// ถ้า topic ว่าง ให้ใช้ basePrompt เป็นพรอมป์หลัก
 	prompt := ""
@@ -245,22 +331,68 @@ func generateContentOnly(topic, basePrompt string, wordLimit int) (string, error
 	ctx, cancel := context.WithTimeout(context.Background(), 45*time.Second)
 	defer cancel()
 
+	started := time.Now()
 	resp, err := textModel.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
-		return "", err
+		return textGeneration{}, err
 	}
 	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("empty text candidates")
+		return textGeneration{}, fmt.Errorf("empty text candidates")
 	}
 
-	content := strings.TrimSpace(fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]))
+	cand := resp.Candidates[0]
+	content := strings.TrimSpace(fmt.Sprintf("%v", cand.Content.Parts[0]))
 	if content == "" {
-		return "", fmt.Errorf("empty content")
+		return textGeneration{}, fmt.Errorf("empty content")
 	}
-	return content, nil
+
+	gen := textGeneration{
+		Content:       content,
+		Model:         textModelName,
+		FinishReason:  cand.FinishReason.String(),
+		SafetyRatings: make([]models.SafetyRating, 0, len(cand.SafetyRatings)),
+		Latency:       time.Since(started),
+	}
+	if resp.UsageMetadata != nil {
+		gen.PromptTokens = int64(resp.UsageMetadata.PromptTokenCount)
+		gen.CandidateTokens = int64(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	for _, r := range cand.SafetyRatings {
+		if r == nil {
+			continue
+		}
+		gen.SafetyRatings = append(gen.SafetyRatings, models.SafetyRating{
+			Category:    r.Category.String(),
+			Probability: r.Probability.String(),
+			Blocked:     r.Blocked,
+		})
+	}
+	return gen, nil
+}
+
+// Gemini text model
+func getTextModel() string {
+	model := os.Getenv("GEMINI_MODEL")
+	if model == "" {
+		model = "gemini-2.5-flash"
+	}
+	return model
+}
+
+// Imagen model (ใช้ตาม docs:predict)
+func getImageModel() string {
+	model := os.Getenv("GEMINI_IMAGE_MODEL")
+	if model == "" {
+		model = "imagen-4.0-generate-001"
+	}
+	return model
+}
+
+// imagePromptFor พรอมป์รูปประกอบโพสต์จาก topic และ style ที่ใช้สร้างเนื้อหา
+func imagePromptFor(topic, style string) string {
+	return fmt.Sprintf("Realistic interior design photo, 16:9, high quality, suitable for social post. Topic: %s. Style: %s", topic, style)
 }
 
-// Gemini/Imagen สร้าง “image” อย่างเดียว แล้วคืนค่าเป็น data URL (base64)
 // Gemini/Imagen สร้าง “image” อย่างเดียว แล้วคืนค่าเป็น data URL (base64)
 func generateImageOnly(imagePrompt string) (string, error) {
 	key := os.Getenv("GEMINI_API_KEY")
@@ -273,11 +405,7 @@ func generateImageOnly(imagePrompt string) (string, error) {
 		return "", fmt.Errorf("image prompt is empty")
 	}
 
-	// Imagen model (ใช้ตาม docs:predict)
-	model := os.Getenv("GEMINI_IMAGE_MODEL")
-	if model == "" {
-		model = "imagen-4.0-generate-001"
-	}
+	model := getImageModel()
 
 	endpoint := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:predict", model)
 
@@ -352,6 +480,11 @@ func generateAIContent() {
 	var config models.AutoConfig
 	_ = collConfig.FindOne(context.TODO(), bson.M{}).Decode(&config)
 
+	if budgetExceeded(config) {
+		log.Println("⏸️ AI Automation หยุดชั่วคราว: ใช้งบรายเดือนเกินที่ตั้งไว้")
+		return
+	}
+
 	// สุ่ม Topic + BasePrompt ใหม่ทุกครั้ง
 	topic := pickRandomString(topicPool)
 	if topic == "" {
@@ -366,17 +499,65 @@ func generateAIContent() {
 
 	log.Println("🤖 AI: กำลังสร้างเนื้อหา+รูป สำหรับหัวข้อ ", topic)
 
-	content, err := generateContentOnly(topic, basePrompt, wordLimit)
+	jobID := newJobID()
+	gen, err := generateText(topic, basePrompt, wordLimit)
 	if err != nil {
 		log.Println("❌ AI Content Generation Error: ", err)
 		return
 	}
+	recordTextUsage(gen, usageProfileAutomation, "automation", jobID)
+	attempts := 1
+
+	// ถ้าคล้ายโพสต์ล่าสุดเกิน threshold ให้สุ่ม style ใหม่แล้วสร้างซ้ำ
+	fingerprint := contentFingerprint(gen.Content)
+	dup, similarity := findNearDuplicate(fingerprint, config, primitive.NilObjectID)
+	for attempt := 0; dup != nil && attempt < maxDuplicateRegenerations; attempt++ {
+		log.Printf("♻️ AI Content ซ้ำกับโพสต์ %s (%.2f) กำลังสร้างใหม่", dup.ID.Hex(), similarity)
+		basePrompt = pickRandomString(basePromptPool)
+		retry, err := generateText(topic, basePrompt, wordLimit)
+		if err != nil {
+			log.Println("❌ AI Content Generation Error: ", err)
+			break
+		}
+		recordTextUsage(retry, usageProfileAutomation, "automation", jobID)
+		attempts++
+		gen = retry
+		fingerprint = contentFingerprint(gen.Content)
+		dup, similarity = findNearDuplicate(fingerprint, config, primitive.NilObjectID)
+	}
+	content := gen.Content
+
+	// ตรวจเนื้อหาก่อนเผยแพร่ ถ้าติดกฎให้เข้าคิว review แทน
+	moderation := moderateContent(gen)
+	if dup != nil {
+		moderation.Reasons = append(moderation.Reasons, fmt.Sprintf("near_duplicate: %s (%.2f)", dup.ID.Hex(), similarity))
+		moderation.Flagged = true
+	}
+	status := models.PostStatusPublished
+	if moderation.Flagged {
+		status = models.PostStatusReview
+		log.Println("🚩 AI Content ถูก flag ส่งเข้าคิว review: ", strings.Join(moderation.Reasons, ", "))
+	}
 
 	// สร้างรูปแยก channel
-	imgPrompt := fmt.Sprintf("Realistic interior design photo, 16:9, high quality, suitable for social post. Topic: %s. Style: %s", topic, basePrompt)
-	imageDataURL, imgErr := generateImageOnly(imgPrompt)
+	provenance := &models.Generation{
+		Topic:        topic,
+		BasePrompt:   basePrompt,
+		WordLimit:    wordLimit,
+		ImagePrompt:  imagePromptFor(topic, basePrompt),
+		Source:       usageProfileAutomation,
+		JobID:        jobID,
+		TextAttempts: attempts,
+	}
+	applyTextProvenance(provenance, gen)
+	imageStarted := time.Now()
+	imageDataURL, imgErr := generateImageOnly(provenance.ImagePrompt)
+	applyImageProvenance(provenance, time.Since(imageStarted), imgErr)
 	if imgErr != nil {
 		log.Println("⚠️ AI Image Generation Error: ", imgErr)
+	} else {
+		recordImageUsage(usageProfileAutomation, "automation", jobID)
+		imageDataURL = watermarkImage(imageDataURL, usageProfileAutomation)
 	}
 
 	img := imageDataURL
@@ -385,31 +566,49 @@ func generateAIContent() {
 	}
 
 	newPost := models.Post{
-		ID:        primitive.NewObjectID(),
-		User:      "Gemini AI Architect",
-		Content:   content,
-		Image:     img,
-		Time:      "เมื่อสักครู่",
-		CreatedAt: time.Now(),
+		ID:          primitive.NewObjectID(),
+		User:        "Gemini AI Architect",
+		Content:     content,
+		Image:       img,
+		CreatedAt:   time.Now(),
+		Status:      status,
+		Moderation:  &moderation,
+		Fingerprint: fingerprint,
+		Topic:       topic,
+		Generation:  provenance,
+		Version:     1,
+		UpdatedBy:   usageProfileAutomation,
+	}
+	if status == models.PostStatusPublished {
+		newPost.PublishedAt = &newPost.CreatedAt
+	}
+	// topic เป็น tag/หมวดหมู่หลัก เสริมด้วย tag ที่ AI เสนอจากเนื้อหา
+	autoTagPost(&newPost, suggestTags(content, usageProfileAutomation, "automation", jobID))
+	applyImageSet(&newPost, mediaSourceGenerated)
+	applySEO(&newPost)
+
+	if err := insertPost(&newPost); err != nil {
+		log.Println("❌ บันทึกโพสต์ไม่สำเร็จ: ", err)
+		return
 	}
-
-	_, _ = database.GetCollection("posts").InsertOne(context.TODO(), newPost)
+	recordRevision(newPost, usageProfileAutomation, revisionReasonGenerated)
+	autoDescribeImageSet(newPost.ImageSet, usageBilling{Profile: usageProfileAutomation, User: "automation", JobID: jobID})
 	log.Println("✅ AI บันทึกโพสต์ใหม่สำเร็จ")
 }
 
 func handleGenerateContent(c *gin.Context) {
 	if geminiClient == nil {
-		c.JSON(500, gin.H{"error": "AI Client is not initialized"})
+		respondError(c, 500, errCodeAIUnavailable, "AI Client is not initialized")
 		return
 	}
 
 	var req struct {
-		Topic     string `json:"topic"`
-		BasePrompt string `json:"basePrompt"`
-		WordLimit int    `json:"wordLimit"`
+		Topic     string `json:"topic" binding:"max=200"`
+		BasePrompt string `json:"basePrompt" binding:"max=2000"`
+		WordLimit int    `json:"wordLimit" binding:"min=0,max=5000"`
+		Variants  int    `json:"variants" binding:"min=0,max=5"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -422,99 +621,247 @@ func handleGenerateContent(c *gin.Context) {
 
 	// ถ้าไม่ได้ส่ง topic/basePrompt มาเลย ให้รองรับโครงเดิมด้วย (fallback)
 	if topic == "" && basePrompt == "" {
-		c.JSON(400, gin.H{"error": "topic/basePrompt is required"})
+		respondError(c, 400, errCodeValidationFailed, "topic/basePrompt is required")
+		return
+	}
+
+	// ขอหลาย variant: result คือ variant ที่คะแนนสูงสุด
+	if req.Variants > 1 {
+		variants, err := generateContentVariants(topic, basePrompt, wl, req.Variants, requestActor(c))
+		if err != nil {
+			log.Printf("❌ Gemini Content Error: %v", err)
+			respondError(c, 500, errCodeAIFailed, "Gemini failed: " + err.Error())
+			return
+		}
+		c.JSON(200, gin.H{"result": variants[0].Content, "variants": variants})
 		return
 	}
 
-	content, err := generateContentOnly(topic, basePrompt, wl)
+	gen, err := generateText(topic, basePrompt, wl)
 	if err != nil {
 		log.Printf("❌ Gemini Content Error: %v", err)
-		c.JSON(500, gin.H{"error": "Gemini failed: " + err.Error()})
+		respondError(c, 500, errCodeAIFailed, "Gemini failed: " + err.Error())
 		return
 	}
+	recordTextUsage(gen, usageProfileEditor, requestActor(c), "")
+	content := gen.Content
 
-	c.JSON(200, gin.H{"result": content})
+	c.JSON(200, gin.H{"result": content, "score": scoreVariant(content, wl)})
 }
 
 func handleGenerateImage(c *gin.Context) {
 	if geminiClient == nil {
-		c.JSON(500, gin.H{"error": "AI Client is not initialized"})
+		respondError(c, 500, errCodeAIUnavailable, "AI Client is not initialized")
 		return
 	}
 
 	var req struct {
-		Prompt string `json:"prompt"`
+		Prompt string `json:"prompt" binding:"required,max=2000"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	prompt := strings.TrimSpace(req.Prompt)
 	if prompt == "" {
-		c.JSON(400, gin.H{"error": "prompt is required"})
+		respondError(c, 400, errCodeValidationFailed, "prompt is required")
 		return
 	}
 
 	imageDataURL, err := generateImageOnly(prompt)
 	if err != nil {
 		log.Printf("❌ Gemini Image Error: %v", err)
-		c.JSON(500, gin.H{"error": "Gemini image failed: " + err.Error()})
+		respondError(c, 500, errCodeAIFailed, "Gemini image failed: " + err.Error())
 		return
 	}
+	recordImageUsage(usageProfileEditor, requestActor(c), "")
+	imageDataURL = watermarkImage(imageDataURL, usageProfileEditor)
 
 	c.JSON(200, gin.H{"image": imageDataURL})
 }
 
 // --- Handlers (GET, POST, PUT, DELETE) ---
 func getPosts(c *gin.Context) {
+	filter, ok := postListFilter(c)
+	if !ok {
+		return
+	}
 	posts := make([]models.Post, 0)
 	coll := database.GetCollection("posts")
 	opts := options.Find().SetSort(bson.M{"created_at": -1})
-	cursor, _ := coll.Find(context.TODO(), bson.M{}, opts)
-	_ = cursor.All(context.TODO(), &posts)
-	c.JSON(200, posts)
+	cursor, err := coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if err := cursor.All(context.TODO(), &posts); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, withRelativeTime(posts, requestLang(c)))
 }
 
 func createPost(c *gin.Context) {
-	var post models.Post
-	if err := c.ShouldBindJSON(&post); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	var req createPostRequest
+	if !bindJSON(c, &req) {
 		return
 	}
-	post.ID = primitive.NewObjectID()
-	post.CreatedAt = time.Now()
-	_, _ = database.GetCollection("posts").InsertOne(context.TODO(), post)
+	post := models.Post{
+		ID:        primitive.NewObjectID(),
+		User:      strings.TrimSpace(req.User),
+		Content:   req.Content,
+		Image:     strings.TrimSpace(req.Image),
+		Title:     strings.TrimSpace(req.Title),
+		Status:    req.Status,
+		CreatedAt: time.Now(),
+		Version:   1,
+		UpdatedBy: requestActor(c),
+		Tags:      normalizeTags(req.Tags),
+	}
+	if len(req.CategoryIDs) > 0 {
+		ids, errs := resolveCategoryIDs(req.CategoryIDs)
+		if len(errs) > 0 {
+			respondError(c, 400, errCodeValidationFailed, "Validation failed", errs...)
+			return
+		}
+		post.CategoryIDs = ids
+	}
+	post.Fingerprint = contentFingerprint(post.Content)
+	if post.Status == "" || post.Status == models.PostStatusPublished {
+		post.PublishedAt = &post.CreatedAt
+	}
+	applyImageSet(&post, mediaSourceUpload)
+	applySEO(&post)
+	if err := insertPost(&post); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	recordRevision(post, post.UpdatedBy, revisionReasonCreated)
+	autoDescribeImageSet(post.ImageSet, usageBilling{Profile: usageProfileEditor, User: post.UpdatedBy})
+	post.Time = relativeTime(postPublishedAt(post), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(post.Version))
 	c.JSON(201, post)
 }
 
 func updatePost(c *gin.Context) {
-	id, _ := primitive.ObjectIDFromHex(c.Param("id"))
-	var data bson.M
-	if err := c.BindJSON(&data); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid JSON"})
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var req updatePostRequest
+	if !bindJSON(c, &req) {
 		return
 	}
-	_, _ = database.GetCollection("posts").UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": data})
-	c.JSON(200, gin.H{"status": "updated"})
+	version, conditional, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+	set, errs := req.toSet()
+	if len(errs) > 0 {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", errs...)
+		return
+	}
+	if len(set) == 0 {
+		respondError(c, 400, errCodeValidationFailed, "No updatable fields in request")
+		return
+	}
+	if image, ok := set["image"].(string); ok {
+		for k, v := range imageFields(image, mediaSourceUpload, id) {
+			set[k] = v
+		}
+	}
+
+	filter := activePostFilter(id)
+	if conditional {
+		filter = versionFilter(id, version)
+	}
+	actor := requestActor(c)
+	ensureBaselineRevision(id)
+	coll := database.GetCollection("posts")
+	res, err := coll.UpdateOne(context.TODO(), filter, touchPost(set, actor))
+	if err != nil {
+		discardStoredImage(set)
+		if mongo.IsDuplicateKeyError(err) {
+			respondError(c, 409, errCodeConflict, "Slug is already used by another post")
+			return
+		}
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		discardStoredImage(set)
+		var current models.Post
+		if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&current); err != nil {
+			respondError(c, 404, errCodeNotFound, "Post not found")
+			return
+		}
+		respondVersionConflict(c, current)
+		return
+	}
+	// โพสต์ที่เพิ่งถูก publish ให้บันทึกเวลาเผยแพร่ (ถ้ายังไม่มี)
+	if req.Status != nil && *req.Status == models.PostStatusPublished {
+		_, _ = coll.UpdateOne(context.TODO(),
+			bson.M{"_id": id, "published_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"published_at": time.Now()}})
+	}
+	refreshPostSEO(id)
+	autoDescribeImageSet(storedImageSet(set), usageBilling{Profile: usageProfileEditor, User: actor})
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = revisionReasonEdit
+	}
+	post, err := recordPostRevision(id, actor, reason)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	post.Time = relativeTime(postPublishedAt(post), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(post.Version))
+	c.JSON(200, post)
 }
 
+// deletePost ย้ายโพสต์ไปถังขยะ ลบจริงภายหลังโดย purgeExpiredTrash
 func deletePost(c *gin.Context) {
-	id, _ := primitive.ObjectIDFromHex(c.Param("id"))
-	_, _ = database.GetCollection("posts").DeleteOne(context.TODO(), bson.M{"_id": id})
-	c.JSON(200, gin.H{"status": "deleted"})
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	res, err := database.GetCollection("posts").UpdateOne(context.TODO(), activePostFilter(id), bson.M{"$set": bson.M{
+		"deleted_at": time.Now(),
+		"deleted_by": requestActor(c),
+	}})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	c.JSON(200, gin.H{"status": "deleted", "retentionDays": trashRetentionDays()})
 }
 
 func getAutoConfig(c *gin.Context) {
 	var config models.AutoConfig
-	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	// ยังไม่เคยบันทึก config คืนค่าว่าง
+	err := database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	if err != nil && err != mongo.ErrNoDocuments {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
 	c.JSON(200, config)
 }
 
 func saveAutoConfig(c *gin.Context) {
 	var config models.AutoConfig
-	if err := c.ShouldBindJSON(&config); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !bindJSON(c, &config) {
+		return
+	}
+	if id := config.Watermark.LogoMediaID; id != "" && !mediaExists(id) {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{
+			Field: "watermark.logoMediaId", Rule: "exists", Message: "logo media not found",
+		})
 		return
 	}
 	_, _ = database.GetCollection("auto_config").UpdateOne(
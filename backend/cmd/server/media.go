@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mediaSourceGenerated = "generated"
+	mediaSourceUpload    = "upload"
+	mediaSourceImport    = "import"
+
+	// ไฟล์ใน media ไม่เปลี่ยนหลังบันทึก (รูปใหม่ได้ id ใหม่) จึง cache ได้ยาว
+	mediaCacheHeader = "public, max-age=31536000, immutable"
+)
+
+var errMediaNotFound = errors.New("media not found")
+
+// mediaBlob ข้อมูลไฟล์จริงหนึ่งไฟล์ _id คือ "<media id>/<key>"
+type mediaBlob struct {
+	ID      string             `bson:"_id"`
+	MediaID primitive.ObjectID `bson:"media_id"`
+	Mime    string             `bson:"mime"`
+	Data    []byte             `bson:"data"`
+}
+
+func ensureMediaIndexes() {
+	_, _ = database.GetCollection("media").Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "post_id", Value: 1}},
+	})
+	_, _ = database.GetCollection("media_blobs").Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "media_id", Value: 1}},
+	})
+	// ใช้หาว่ารูปถูกใช้ในโพสต์ไหนบ้าง
+	_, _ = database.GetCollection("posts").Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "image_set.media_id", Value: 1}},
+	})
+}
+
+func mediaURL(id primitive.ObjectID, key string) string {
+	return publicBaseURL() + "/media/" + id.Hex() + "/" + key
+}
+
+// mediaIDFromURL แยก media id จาก URL ที่ mediaURL สร้าง
+func mediaIDFromURL(s string) (primitive.ObjectID, bool) {
+	rest, ok := strings.CutPrefix(s, publicBaseURL()+"/media/")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	hex, _, _ := strings.Cut(rest, "/")
+	id, err := primitive.ObjectIDFromHex(hex)
+	return id, err == nil
+}
+
+// saveMedia ส่งรูปเข้า pipeline แล้วเก็บต้นฉบับกับรูปย่อทุกขนาด
+// m คือข้อมูลที่ผู้เรียกกำหนดเอง (Source, PostID, Filename, ...) ส่วนขนาด/ไฟล์จะถูกเติมให้
+func saveMedia(data []byte, m models.Media) (models.Media, error) {
+	processed, err := processImage(data)
+	if err != nil {
+		return models.Media{}, err
+	}
+
+	m.ID = primitive.NewObjectID()
+	m.Mime = processed.Original.Mime
+	m.Width = processed.Original.Width
+	m.Height = processed.Original.Height
+	m.Size = len(processed.Original.Data)
+	m.Blurhash = processed.Blurhash
+	m.Variants = nil
+	m.CreatedAt = time.Now()
+	blobs := make([]interface{}, 0, len(processed.Variants)+1)
+	for _, f := range append([]encodedImage{processed.Original}, processed.Variants...) {
+		m.Variants = append(m.Variants, models.MediaVariant{
+			Key:    f.Key,
+			Mime:   f.Mime,
+			Width:  f.Width,
+			Height: f.Height,
+			Size:   len(f.Data),
+		})
+		blobs = append(blobs, mediaBlob{ID: m.ID.Hex() + "/" + f.Key, MediaID: m.ID, Mime: f.Mime, Data: f.Data})
+	}
+
+	if _, err := database.GetCollection("media_blobs").InsertMany(context.TODO(), blobs); err != nil {
+		return models.Media{}, err
+	}
+	if _, err := database.GetCollection("media").InsertOne(context.TODO(), m); err != nil {
+		_, _ = database.GetCollection("media_blobs").DeleteMany(context.TODO(), bson.M{"media_id": m.ID})
+		return models.Media{}, err
+	}
+	return m, nil
+}
+
+// imageSetFor สร้าง srcset จากรูปย่อ (ไม่รวมต้นฉบับ) Src คือรูปย่อที่ใหญ่ที่สุด
+func imageSetFor(m models.Media) *models.ImageSet {
+	set := &models.ImageSet{
+		MediaID:   m.ID,
+		Width:     m.Width,
+		Height:    m.Height,
+		Blurhash:  m.Blurhash,
+		Alt:       m.Alt,
+		AltEN:     m.AltEN,
+		Caption:   m.Caption,
+		CaptionEN: m.CaptionEN,
+		Sources:   make([]models.ImageSource, 0, len(m.Variants)),
+	}
+	srcset := make([]string, 0, len(m.Variants))
+	for _, v := range m.Variants {
+		if strings.HasPrefix(v.Key, "original") {
+			continue
+		}
+		url := mediaURL(m.ID, v.Key)
+		set.Sources = append(set.Sources, models.ImageSource{URL: url, Mime: v.Mime, Width: v.Width, Height: v.Height, Size: v.Size})
+		srcset = append(srcset, fmt.Sprintf("%s %dw", url, v.Width))
+		set.Src = url
+	}
+	set.Srcset = strings.Join(srcset, ", ")
+	return set
+}
+
+// storeImage ย้ายรูป data URL เข้า media แล้วคืน URL ของรูปย่อที่ใหญ่ที่สุด
+// URL ของ media เดิมจะได้ ImageSet เดิมกลับมา ส่วน URL ภายนอก/รูปที่ประมวลผลไม่ได้จะคืนค่าเดิมกับ nil
+// ผู้เรียกเรียก autoDescribeImageSet หลังบันทึกโพสต์แล้วเพื่อสร้าง alt text เบื้องหลัง
+func storeImage(image, source string, postID primitive.ObjectID) (string, *models.ImageSet) {
+	if _, data, ok := parseDataURL(image); ok {
+		m, err := saveMedia(data, models.Media{Source: source, PostID: &postID})
+		if err != nil {
+			log.Printf("⚠️ ประมวลผลรูปของโพสต์ %s ไม่สำเร็จ เก็บเป็น data URL เดิม: %v", postID.Hex(), err)
+			return image, nil
+		}
+		set := imageSetFor(m)
+		set.Stored = true
+		return set.Src, set
+	}
+	if id, ok := mediaIDFromURL(image); ok {
+		var m models.Media
+		if err := database.GetCollection("media").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&m); err == nil {
+			return image, imageSetFor(m)
+		}
+	}
+	return image, nil
+}
+
+// applyImageSet ใช้กับโพสต์ใหม่ก่อน insert
+func applyImageSet(p *models.Post, source string) {
+	p.Image, p.ImageSet = storeImage(p.Image, source, p.ID)
+}
+
+// imageFields $set ของรูปใหม่ (image_set เป็น null ถ้ารูปไม่ได้อยู่ใน media)
+func imageFields(image, source string, postID primitive.ObjectID) bson.M {
+	img, set := storeImage(image, source, postID)
+	return bson.M{"image": img, "image_set": set}
+}
+
+// storedImageSet image_set จาก $set ที่ imageFields สร้าง
+func storedImageSet(set bson.M) *models.ImageSet {
+	imageSet, _ := set["image_set"].(*models.ImageSet)
+	return imageSet
+}
+
+// discardImageSet ลบรูปที่ storeImage เพิ่งเก็บลง media เมื่อบันทึกโพสต์ไม่สำเร็จ กัน media กำพร้า
+// รูปที่อ้างถึง media เดิม (URL) ไม่ถูกลบ
+func discardImageSet(imageSet *models.ImageSet) {
+	if imageSet == nil || !imageSet.Stored {
+		return
+	}
+	if err := deleteMediaFiles([]primitive.ObjectID{imageSet.MediaID}); err != nil {
+		log.Printf("⚠️ ลบ media %s ที่ไม่ได้ใช้ไม่สำเร็จ: %v", imageSet.MediaID.Hex(), err)
+	}
+}
+
+// discardStoredImage discardImageSet สำหรับ $set ที่ imageFields สร้าง
+func discardStoredImage(set bson.M) {
+	discardImageSet(storedImageSet(set))
+}
+
+func mediaExists(hex string) bool {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return false
+	}
+	n, _ := database.GetCollection("media").CountDocuments(context.TODO(), bson.M{"_id": id})
+	return n > 0
+}
+
+func loadMediaBlob(id primitive.ObjectID, key string) (string, []byte, bool) {
+	var blob mediaBlob
+	if err := database.GetCollection("media_blobs").FindOne(context.TODO(), bson.M{"_id": id.Hex() + "/" + key}).Decode(&blob); err != nil {
+		return "", nil, false
+	}
+	return blob.Mime, blob.Data, true
+}
+
+// syncPostImageSets อัปเดต image_set ที่ฝังในโพสต์ให้ตรงกับ media (เช่นหลังแก้ alt) แล้วคำนวณ SEO ใหม่
+func syncPostImageSets(m models.Media) {
+	coll := database.GetCollection("posts")
+	filter := bson.M{"image_set.media_id": m.ID}
+	cursor, err := coll.Find(context.TODO(), filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return
+	}
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	_ = cursor.All(context.TODO(), &rows)
+	if len(rows) == 0 {
+		return
+	}
+	if _, err := coll.UpdateMany(context.TODO(), filter, bson.M{"$set": bson.M{"image_set": imageSetFor(m)}}); err != nil {
+		log.Printf("⚠️ อัปเดตรูปในโพสต์ที่ใช้ media %s ไม่สำเร็จ: %v", m.ID.Hex(), err)
+		return
+	}
+	for _, r := range rows {
+		refreshPostSEO(r.ID)
+	}
+}
+
+// loadMediaOriginal ไฟล์ต้นฉบับของ media ใช้ตอน export และเป็นโลโก้ watermark
+func loadMediaOriginal(id primitive.ObjectID) (string, []byte, bool) {
+	var blob mediaBlob
+	filter := bson.M{"media_id": id, "_id": bson.M{"$regex": "/original"}}
+	if err := database.GetCollection("media_blobs").FindOne(context.TODO(), filter).Decode(&blob); err != nil {
+		return "", nil, false
+	}
+	return blob.Mime, blob.Data, true
+}
+
+// deletePostMedia ลบรูปทุกรูปที่สร้างให้โพสต์ (รวมรูปเก่าที่ถูกแทนที่แล้ว)
+// ยกเว้นรูปที่ถูกนำไปใช้ซ้ำในโพสต์อื่นหรือเป็นโลโก้ watermark
+func deletePostMedia(postIDs []primitive.ObjectID) error {
+	cursor, err := database.GetCollection("media").Find(context.TODO(),
+		bson.M{"post_id": bson.M{"$in": postIDs}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil || len(rows) == 0 {
+		return err
+	}
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		if n, _ := countMediaUsage(r.ID, postIDs); n == 0 {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return deleteMediaFiles(ids)
+}
+
+func deleteMediaFiles(ids []primitive.ObjectID) error {
+	if _, err := database.GetCollection("media_blobs").DeleteMany(context.TODO(), bson.M{"media_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+	_, err := database.GetCollection("media").DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// getMediaFile ส่งไฟล์รูปจาก media_blobs
+func getMediaFile(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var blob mediaBlob
+	if err := database.GetCollection("media_blobs").FindOne(context.TODO(), bson.M{"_id": id.Hex() + "/" + c.Param("file")}).Decode(&blob); err != nil {
+		respondError(c, 404, errCodeNotFound, "Media not found")
+		return
+	}
+
+	etag := `"` + blob.ID + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", mediaCacheHeader)
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			if t := strings.TrimSpace(tag); t == etag || t == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	c.Data(http.StatusOK, blob.Mime, blob.Data)
+}
+
+// backfillMediaImages ย้ายรูป data URL ในโพสต์เก่าเข้า media (ไม่นับเป็นการแก้ไขโพสต์)
+func backfillMediaImages() {
+	coll := database.GetCollection("posts")
+	cursor, err := coll.Find(context.TODO(),
+		bson.M{"image": bson.M{"$regex": "^data:"}},
+		options.Find().SetProjection(bson.M{"_id": 1, "image": 1, "generation": 1}))
+	if err != nil {
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	moved := 0
+	for cursor.Next(context.TODO()) {
+		var p models.Post
+		if err := cursor.Decode(&p); err != nil {
+			continue
+		}
+		// งานย้ายรูปตอนเริ่ม server ไม่มีผู้เรียก alt text จึงคิดกับ automation (และ job ที่สร้างโพสต์ถ้ามี)
+		source := mediaSourceUpload
+		billing := usageBilling{Profile: usageProfileAutomation, User: "automation"}
+		if p.Generation != nil {
+			source = mediaSourceGenerated
+			billing.JobID = p.Generation.JobID
+		}
+		fields := imageFields(p.Image, source, p.ID)
+		if fields["image_set"] == (*models.ImageSet)(nil) {
+			continue
+		}
+		res, err := coll.UpdateOne(context.TODO(), bson.M{"_id": p.ID, "image": p.Image}, bson.M{"$set": fields})
+		if err != nil || res.MatchedCount == 0 {
+			// โพสต์ถูกแก้รูปหรือลบไประหว่างนั้น media ที่เพิ่งเก็บจึงไม่มีใครใช้
+			discardStoredImage(fields)
+			continue
+		}
+		refreshPostSEO(p.ID)
+		if altTextBackfillEnabled() {
+			autoDescribeImageSet(storedImageSet(fields), billing)
+		}
+		moved++
+	}
+	if moved > 0 {
+		log.Printf("🖼️ ย้ายรูป data URL ของโพสต์เก่าเข้า media %d โพสต์", moved)
+	}
+}
@@ -0,0 +1,557 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	mediaPageSize         = 30
+	mediaMaxPageSize      = 100
+	defaultMediaUploadMB  = 10
+	mediaFilenameMaxRunes = 200
+)
+
+// mediaItem media พร้อม URL สำหรับใช้ในโพสต์และจำนวนโพสต์ที่ใช้รูปนี้
+type mediaItem struct {
+	models.Media
+	URL      string           `json:"url"`
+	ImageSet *models.ImageSet `json:"imageSet"`
+	Usage    int64            `json:"usage"`
+}
+
+// mediaUsage โพสต์ที่ใช้รูป (รวมโพสต์ในถังขยะและ revision เก่า เพราะยังกู้คืนได้)
+type mediaUsage struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Status  string `json:"status,omitempty"`
+	Deleted bool   `json:"deleted"`
+	// RevisionOnly โพสต์ปัจจุบันไม่ได้ใช้แล้ว แต่ยังมี revision ที่อ้างถึงรูปนี้
+	RevisionOnly bool `json:"revisionOnly"`
+}
+
+// updateMediaRequest ฟิลด์ที่ editor แก้ได้ ฟิลด์ที่เป็น nil จะไม่ถูกแตะ
+type updateMediaRequest struct {
+	Filename  *string   `json:"filename" binding:"omitempty,min=1,max=200"`
+	Alt       *string   `json:"alt" binding:"omitempty,max=500"`
+	AltEN     *string   `json:"altEn" binding:"omitempty,max=500"`
+	Caption   *string   `json:"caption" binding:"omitempty,max=500"`
+	CaptionEN *string   `json:"captionEn" binding:"omitempty,max=500"`
+	Tags      *[]string `json:"tags" binding:"omitempty,max=10,dive,max=40"`
+}
+
+// mediaUploadLimit ขนาดไฟล์อัปโหลดสูงสุด (byte) ตั้งได้ด้วย MEDIA_MAX_UPLOAD_MB
+func mediaUploadLimit() int64 {
+	mb, err := strconv.Atoi(os.Getenv("MEDIA_MAX_UPLOAD_MB"))
+	if err != nil || mb <= 0 {
+		mb = defaultMediaUploadMB
+	}
+	return int64(mb) << 20
+}
+
+func toMediaItem(m models.Media, usage int64) mediaItem {
+	set := imageSetFor(m)
+	return mediaItem{Media: m, URL: set.Src, ImageSet: set, Usage: usage}
+}
+
+// mediaRefFilter เงื่อนไขหาเอกสารที่อ้างถึงรูปใน ids ทั้ง image_set และรูปของ variant (เก็บเป็น URL)
+// prefix เป็น "snapshot." เมื่อค้นใน post_revisions
+func mediaRefFilter(prefix string, ids []primitive.ObjectID) bson.M {
+	hexes := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hexes = append(hexes, id.Hex())
+	}
+	pattern := "^" + regexp.QuoteMeta(publicBaseURL()+"/media/") + "(" + strings.Join(hexes, "|") + ")/"
+	return bson.M{"$or": bson.A{
+		bson.M{prefix + "image_set.media_id": bson.M{"$in": ids}},
+		bson.M{prefix + "variants.image": primitive.Regex{Pattern: pattern}},
+	}}
+}
+
+// anyMediaRefFilter เหมือน mediaRefFilter แต่ตรงกับเอกสารที่อ้างถึงรูปใดก็ได้ในคลัง
+func anyMediaRefFilter(prefix string) bson.M {
+	pattern := "^" + regexp.QuoteMeta(publicBaseURL()+"/media/") + "[0-9a-f]{24}/"
+	return bson.M{"$or": bson.A{
+		bson.M{prefix + "image_set.media_id": bson.M{"$exists": true}},
+		bson.M{prefix + "variants.image": primitive.Regex{Pattern: pattern}},
+	}}
+}
+
+// postMediaIDs media ทุกรูปที่โพสต์ (หรือ snapshot) อ้างถึง
+func postMediaIDs(p models.Post) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, 1+len(p.Variants))
+	if p.ImageSet != nil && !p.ImageSet.MediaID.IsZero() {
+		ids = append(ids, p.ImageSet.MediaID)
+	}
+	for _, v := range p.Variants {
+		if id, ok := mediaIDFromURL(v.Image); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// mediaReferences โพสต์ที่อ้างถึงแต่ละรูป: รูปหลัก, รูปของ variant (รวมโพสต์ในถังขยะ)
+// และ snapshot ใน post_revisions ที่กู้คืนกลับมาได้ คืน media id -> post id -> อ้างถึงเฉพาะใน revision
+func mediaReferences(ids []primitive.ObjectID) (map[primitive.ObjectID]map[primitive.ObjectID]bool, error) {
+	refs := make(map[primitive.ObjectID]map[primitive.ObjectID]bool, len(ids))
+	add := func(p models.Post, postID primitive.ObjectID, revision bool) {
+		for _, id := range postMediaIDs(p) {
+			if !containsObjectID(ids, id) {
+				continue
+			}
+			if refs[id] == nil {
+				refs[id] = make(map[primitive.ObjectID]bool)
+			}
+			if only, seen := refs[id][postID]; !seen || only {
+				refs[id][postID] = revision
+			}
+		}
+	}
+
+	if err := scanMediaReferences(mediaRefFilter("", ids), mediaRefFilter("snapshot.", ids), add); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// scanMediaReferences เรียก visit กับทุกโพสต์ที่ตรง postFilter และทุก snapshot ใน post_revisions ที่ตรง revisionFilter
+func scanMediaReferences(postFilter, revisionFilter bson.M, visit func(p models.Post, postID primitive.ObjectID, revision bool)) error {
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), postFilter,
+		options.Find().SetProjection(bson.M{"_id": 1, "image_set.media_id": 1, "variants.image": 1}))
+	if err != nil {
+		return err
+	}
+	posts := make([]models.Post, 0)
+	if err := cursor.All(context.TODO(), &posts); err != nil {
+		return err
+	}
+	for _, p := range posts {
+		visit(p, p.ID, false)
+	}
+
+	cursor, err = database.GetCollection("post_revisions").Find(context.TODO(), revisionFilter,
+		options.Find().SetProjection(bson.M{"post_id": 1, "snapshot.image_set.media_id": 1, "snapshot.variants.image": 1}))
+	if err != nil {
+		return err
+	}
+	revisions := make([]models.PostRevision, 0)
+	if err := cursor.All(context.TODO(), &revisions); err != nil {
+		return err
+	}
+	for _, r := range revisions {
+		visit(r.Snapshot, r.PostID, true)
+	}
+	return nil
+}
+
+// referencedMediaIDs รูปทุกรูปที่ยังถูกใช้ตามเกณฑ์เดียวกับ countMediaUsage (โพสต์, variant, revision และโลโก้ watermark)
+// ?unused=true จึงแสดงเฉพาะรูปที่ deleteMedia ลบได้จริง
+func referencedMediaIDs() ([]primitive.ObjectID, error) {
+	seen := make(map[primitive.ObjectID]bool)
+	used := make([]primitive.ObjectID, 0)
+	mark := func(id primitive.ObjectID) {
+		if !seen[id] {
+			seen[id] = true
+			used = append(used, id)
+		}
+	}
+	err := scanMediaReferences(anyMediaRefFilter(""), anyMediaRefFilter("snapshot."), func(p models.Post, _ primitive.ObjectID, _ bool) {
+		for _, id := range postMediaIDs(p) {
+			mark(id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if id, ok := watermarkLogoID(); ok {
+		mark(id)
+	}
+	return used, nil
+}
+
+// countMediaUsage จำนวนโพสต์ที่อ้างถึงรูป (ไม่นับ exclude) บวกหนึ่งถ้าเป็นโลโก้ watermark
+func countMediaUsage(id primitive.ObjectID, exclude []primitive.ObjectID) (int64, error) {
+	refs, err := mediaReferences([]primitive.ObjectID{id})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for postID := range refs[id] {
+		if !containsObjectID(exclude, postID) {
+			n++
+		}
+	}
+	if isWatermarkLogo(id) {
+		n++
+	}
+	return n, nil
+}
+
+func isWatermarkLogo(id primitive.ObjectID) bool {
+	logo, ok := watermarkLogoID()
+	return ok && logo == id
+}
+
+// watermarkLogoID media ที่ตั้งเป็นโลโก้ watermark (ok = false ถ้าไม่ได้ตั้ง)
+func watermarkLogoID() (primitive.ObjectID, bool) {
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	id, err := primitive.ObjectIDFromHex(config.Watermark.LogoMediaID)
+	return id, err == nil
+}
+
+// mediaUsageCounts จำนวนโพสต์ที่อ้างถึงแต่ละรูปในหน้าเดียว (ค้น posts และ post_revisions อย่างละครั้ง)
+func mediaUsageCounts(ids []primitive.ObjectID) map[primitive.ObjectID]int64 {
+	counts := make(map[primitive.ObjectID]int64, len(ids))
+	if len(ids) == 0 {
+		return counts
+	}
+	refs, err := mediaReferences(ids)
+	if err != nil {
+		return counts
+	}
+	for id, posts := range refs {
+		counts[id] = int64(len(posts))
+	}
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	if logo, err := primitive.ObjectIDFromHex(config.Watermark.LogoMediaID); err == nil {
+		if containsObjectID(ids, logo) {
+			counts[logo]++
+		}
+	}
+	return counts
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadMedia อัปโหลดรูปเข้าคลัง (multipart field "file" พร้อม alt และ tags คั่นด้วย comma)
+// ตรวจชนิดไฟล์จาก byte จริงและจำกัดขนาดตาม MEDIA_MAX_UPLOAD_MB
+func uploadMedia(c *gin.Context) {
+	limit := mediaUploadLimit()
+	// เผื่อพื้นที่ให้ส่วนหัวของ multipart และฟิลด์อื่น
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, 413, errCodePayloadTooLarge, fmt.Sprintf("file is larger than %d MB", limit>>20))
+			return
+		}
+		respondError(c, 400, errCodeValidationFailed, "file is required")
+		return
+	}
+	if fh.Size > limit {
+		respondError(c, 413, errCodePayloadTooLarge, fmt.Sprintf("file is larger than %d MB", limit>>20))
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, 400, errCodeInvalidBody, err.Error())
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		respondError(c, 400, errCodeInvalidBody, err.Error())
+		return
+	}
+	if int64(len(data)) > limit {
+		respondError(c, 413, errCodePayloadTooLarge, fmt.Sprintf("file is larger than %d MB", limit>>20))
+		return
+	}
+	if sniffImageMime(data) == "" {
+		respondError(c, 415, errCodeUnsupportedMedia, "file must be a PNG, JPEG, GIF or WebP image")
+		return
+	}
+
+	filename := strings.TrimSpace(path.Base(strings.ReplaceAll(fh.Filename, "\\", "/")))
+	if r := []rune(filename); len(r) > mediaFilenameMaxRunes {
+		filename = string(r[:mediaFilenameMaxRunes])
+	}
+	alt := strings.TrimSpace(c.PostForm("alt"))
+	if len([]rune(alt)) > 500 {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{Field: "alt", Rule: "max", Param: "500", Message: "alt must be at most 500 characters"})
+		return
+	}
+	var tags []string
+	for _, v := range c.PostFormArray("tags") {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+
+	meta := models.Media{
+		Source:     mediaSourceUpload,
+		Filename:   filename,
+		Alt:        alt,
+		Tags:       normalizeTags(tags),
+		UploadedBy: requestActor(c),
+	}
+	if alt != "" {
+		meta.AltSource = "editor"
+	}
+	m, err := saveMedia(data, meta)
+	if err != nil {
+		if errors.Is(err, errUnsupportedImage) {
+			respondError(c, 415, errCodeUnsupportedMedia, err.Error())
+			return
+		}
+		respondError(c, 400, errCodeValidationFailed, err.Error())
+		return
+	}
+	autoDescribeMedia(m.ID, usageBilling{Profile: usageProfileEditor, User: requestActor(c)})
+	c.JSON(201, toMediaItem(m, 0))
+}
+
+// getMediaLibrary รายการรูปในคลัง ค้นหาด้วย ?q= (ชื่อไฟล์/alt), ?tag= (ซ้ำได้), ?source=, ?unused=true
+func getMediaLibrary(c *gin.Context) {
+	filter := bson.M{}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(q), Options: "i"}
+		filter["$or"] = bson.A{bson.M{"filename": pattern}, bson.M{"alt": pattern}, bson.M{"tags": normalizeTag(q)}}
+	}
+	if tags := normalizeTags(c.QueryArray("tag")); len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+	if source := c.Query("source"); source != "" {
+		filter["source"] = source
+	}
+	if c.Query("unused") == "true" {
+		used, err := referencedMediaIDs()
+		if err != nil {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		filter["_id"] = bson.M{"$nin": used}
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(mediaPageSize)))
+	if limit < 1 || limit > mediaMaxPageSize {
+		limit = mediaPageSize
+	}
+
+	coll := database.GetCollection("media")
+	total, err := coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	media := make([]models.Media, 0)
+	if err := cursor.All(context.TODO(), &media); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(media))
+	for _, m := range media {
+		ids = append(ids, m.ID)
+	}
+	counts := mediaUsageCounts(ids)
+	items := make([]mediaItem, 0, len(media))
+	for _, m := range media {
+		items = append(items, toMediaItem(m, counts[m.ID]))
+	}
+	c.JSON(200, gin.H{"items": items, "page": page, "limit": limit, "total": total})
+}
+
+func findMedia(c *gin.Context) (models.Media, bool) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return models.Media{}, false
+	}
+	var m models.Media
+	if err := database.GetCollection("media").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&m); err != nil {
+		respondError(c, 404, errCodeNotFound, "Media not found")
+		return models.Media{}, false
+	}
+	return m, true
+}
+
+// mediaUsedBy โพสต์ที่อ้างถึงรูปนี้ รวมโพสต์ที่อ้างถึงเฉพาะใน revision เก่า
+func mediaUsedBy(id primitive.ObjectID) ([]mediaUsage, error) {
+	refs, err := mediaReferences([]primitive.ObjectID{id})
+	if err != nil {
+		return nil, err
+	}
+	used := make([]mediaUsage, 0, len(refs[id]))
+	if len(refs[id]) == 0 {
+		return used, nil
+	}
+	postIDs := make([]primitive.ObjectID, 0, len(refs[id]))
+	for postID := range refs[id] {
+		postIDs = append(postIDs, postID)
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetProjection(bson.M{"_id": 1, "title": 1, "content": 1, "status": 1, "deleted_at": 1})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), bson.M{"_id": bson.M{"$in": postIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]models.Post, 0)
+	if err := cursor.All(context.TODO(), &posts); err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		used = append(used, mediaUsage{
+			ID:           p.ID.Hex(),
+			Title:        postTitle(p),
+			Status:       p.Status,
+			Deleted:      p.DeletedAt != nil,
+			RevisionOnly: refs[id][p.ID],
+		})
+		delete(refs[id], p.ID)
+	}
+	// revision ที่โพสต์ถูกลบไปแล้วแต่ยังค้างอยู่ ก็ยังถือว่าใช้อยู่
+	for postID := range refs[id] {
+		used = append(used, mediaUsage{ID: postID.Hex(), Deleted: true, RevisionOnly: true})
+	}
+	return used, nil
+}
+
+// getMedia รายละเอียดรูปพร้อมรายการโพสต์ที่ใช้
+func getMedia(c *gin.Context) {
+	m, ok := findMedia(c)
+	if !ok {
+		return
+	}
+	used, err := mediaUsedBy(m.ID)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	usage := int64(len(used))
+	logo := isWatermarkLogo(m.ID)
+	if logo {
+		usage++
+	}
+	c.JSON(200, gin.H{"media": toMediaItem(m, usage), "usedBy": used, "watermarkLogo": logo})
+}
+
+// updateMedia แก้ชื่อไฟล์/alt/caption/tags และอัปเดตโพสต์ที่ใช้รูปนี้อยู่
+func updateMedia(c *gin.Context) {
+	m, ok := findMedia(c)
+	if !ok {
+		return
+	}
+	var req updateMediaRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	set := bson.M{}
+	if req.Filename != nil {
+		m.Filename = strings.TrimSpace(*req.Filename)
+		set["filename"] = m.Filename
+	}
+	// ข้อความที่ editor แก้เองจะไม่ถูก AI เขียนทับ (describeMedia เติมเฉพาะช่องว่าง)
+	texts := false
+	for _, f := range []struct {
+		field string
+		dst   *string
+		value *string
+	}{
+		{"alt", &m.Alt, req.Alt},
+		{"alt_en", &m.AltEN, req.AltEN},
+		{"caption", &m.Caption, req.Caption},
+		{"caption_en", &m.CaptionEN, req.CaptionEN},
+	} {
+		if f.value != nil {
+			*f.dst = strings.TrimSpace(*f.value)
+			set[f.field] = *f.dst
+			texts = true
+		}
+	}
+	if texts {
+		m.AltSource = "editor"
+		set["alt_source"] = m.AltSource
+	}
+	if req.Tags != nil {
+		m.Tags = normalizeTags(*req.Tags)
+		set["tags"] = m.Tags
+	}
+	if len(set) == 0 {
+		respondError(c, 400, errCodeValidationFailed, "No updatable fields in request")
+		return
+	}
+	now := time.Now()
+	m.UpdatedAt = &now
+	set["updated_at"] = now
+	if _, err := database.GetCollection("media").UpdateOne(context.TODO(), bson.M{"_id": m.ID}, bson.M{"$set": set}); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if texts {
+		syncPostImageSets(m)
+	}
+	usage, _ := countMediaUsage(m.ID, nil)
+	c.JSON(200, toMediaItem(m, usage))
+}
+
+// deleteMedia ลบรูปออกจากคลัง ไม่ยอมลบถ้ายังมีโพสต์ใช้อยู่ (รวมโพสต์ในถังขยะ, variant และ revision) หรือเป็นโลโก้ watermark
+func deleteMedia(c *gin.Context) {
+	m, ok := findMedia(c)
+	if !ok {
+		return
+	}
+	used, err := mediaUsedBy(m.ID)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if len(used) > 0 {
+		c.AbortWithStatusJSON(409, gin.H{
+			"error":  fmt.Sprintf("Media is used by %d post(s)", len(used)),
+			"code":   errCodeConflict,
+			"usedBy": used,
+		})
+		return
+	}
+	if isWatermarkLogo(m.ID) {
+		respondError(c, 409, errCodeConflict, "Media is used as the watermark logo")
+		return
+	}
+	if err := deleteMediaFiles([]primitive.ObjectID{m.ID}); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"status": "deleted"})
+}
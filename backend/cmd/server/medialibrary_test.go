@@ -0,0 +1,74 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPostMediaIDsIncludesVariants(t *testing.T) {
+	cover, variant := primitive.NewObjectID(), primitive.NewObjectID()
+	p := models.Post{
+		ImageSet: &models.ImageSet{MediaID: cover},
+		Variants: []models.PostVariant{
+			{Key: "A", Image: mediaURL(variant, "original")},
+			{Key: "B", Image: "data:image/png;base64,AAAA"},
+			{Key: "C"},
+		},
+	}
+	got := postMediaIDs(p)
+	if len(got) != 2 || got[0] != cover || got[1] != variant {
+		t.Fatalf("postMediaIDs = %v, want [%v %v]", got, cover, variant)
+	}
+	if got := postMediaIDs(models.Post{}); len(got) != 0 {
+		t.Fatalf("postMediaIDs(no image) = %v, want empty", got)
+	}
+}
+
+func TestMediaRefFilterVariantPattern(t *testing.T) {
+	id, other := primitive.NewObjectID(), primitive.NewObjectID()
+	f := mediaRefFilter("snapshot.", []primitive.ObjectID{id})
+	or := f["$or"].(bson.A)
+	if _, ok := or[0].(bson.M)["snapshot.image_set.media_id"]; !ok {
+		t.Fatalf("filter %v does not check snapshot.image_set.media_id", f)
+	}
+	re := regexp.MustCompile(or[1].(bson.M)["snapshot.variants.image"].(primitive.Regex).Pattern)
+	for _, tc := range []struct {
+		image string
+		want  bool
+	}{
+		{mediaURL(id, "w640.jpg"), true},
+		{mediaURL(other, "w640.jpg"), false},
+		{"https://cdn.example.com/media/" + id.Hex() + "/original", false},
+		{"data:image/png;base64,AAAA", false},
+	} {
+		if got := re.MatchString(tc.image); got != tc.want {
+			t.Errorf("match %q = %v, want %v", tc.image, got, tc.want)
+		}
+	}
+}
+
+func TestAnyMediaRefFilterVariantPattern(t *testing.T) {
+	f := anyMediaRefFilter("")
+	or := f["$or"].(bson.A)
+	if _, ok := or[0].(bson.M)["image_set.media_id"]; !ok {
+		t.Fatalf("filter %v does not check image_set.media_id", f)
+	}
+	re := regexp.MustCompile(or[1].(bson.M)["variants.image"].(primitive.Regex).Pattern)
+	for _, tc := range []struct {
+		url  string
+		want bool
+	}{
+		{mediaURL(primitive.NewObjectID(), "w640.jpg"), true},
+		{"https://images.unsplash.com/photo-1?w=800", false},
+		{"data:image/png;base64,AAAA", false},
+	} {
+		if got := re.MatchString(tc.url); got != tc.want {
+			t.Errorf("pattern %q match %q = %v, want %v", re, tc.url, got, tc.want)
+		}
+	}
+}
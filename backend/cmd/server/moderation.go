@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type moderationRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// กฎ regex พื้นฐาน: ราคา และการกล่าวอ้างทางการแพทย์
+// \b ของ Go นับเฉพาะตัวอักษร ASCII จึงใช้หลังคำไทยไม่ได้
+var moderationRules = []moderationRule{
+	{Name: "price", Pattern: regexp.MustCompile(`(?i)(฿\s*\d|\d[\d,.]*\s*(?:บาท|(?i:baht|thb)\b)|ราคา\s*\d)`)},
+	{Name: "medical_claim", Pattern: regexp.MustCompile(`(?i)(รักษา(โรค|หาย)|หายขาด|ป้องกันมะเร็ง|ต้านมะเร็ง|ฆ่าเชื้อโรค\s*\d+%|\bcures?\b|\bheals?\b|prevents? cancer)`)},
+}
+
+// getModerationList อ่านรายการคั่นด้วย comma จาก env
+func getModerationList(key string) []string {
+	list := make([]string, 0)
+	for _, s := range strings.Split(os.Getenv(key), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// moderateContent ตรวจเนื้อหาจาก Gemini ก่อนบันทึก ถ้า Flagged ต้องส่งเข้าคิว review
+func moderateContent(gen textGeneration) models.Moderation {
+	m := models.Moderation{
+		Reasons:       make([]string, 0),
+		FinishReason:  gen.FinishReason,
+		SafetyRatings: gen.SafetyRatings,
+		CheckedAt:     time.Now(),
+	}
+
+	if gen.FinishReason != "" &&
+		gen.FinishReason != genai.FinishReasonStop.String() &&
+		gen.FinishReason != genai.FinishReasonUnspecified.String() {
+		m.Reasons = append(m.Reasons, "finish_reason: "+gen.FinishReason)
+	}
+
+	for _, r := range gen.SafetyRatings {
+		if r.Blocked ||
+			r.Probability == genai.HarmProbabilityMedium.String() ||
+			r.Probability == genai.HarmProbabilityHigh.String() {
+			m.Reasons = append(m.Reasons, "safety: "+r.Category+" "+r.Probability)
+		}
+	}
+
+	lower := strings.ToLower(gen.Content)
+	for _, word := range getModerationList("MODERATION_BLOCKLIST") {
+		if strings.Contains(lower, strings.ToLower(word)) {
+			m.Reasons = append(m.Reasons, "blocklist: "+word)
+		}
+	}
+	for _, name := range getModerationList("MODERATION_COMPETITORS") {
+		if strings.Contains(lower, strings.ToLower(name)) {
+			m.Reasons = append(m.Reasons, "competitor: "+name)
+		}
+	}
+
+	for _, rule := range moderationRules {
+		if match := rule.Pattern.FindString(gen.Content); match != "" {
+			m.Reasons = append(m.Reasons, rule.Name+": "+match)
+		}
+	}
+
+	m.Flagged = len(m.Reasons) > 0
+	return m
+}
+
+// requestActor ชื่อผู้ใช้จาก header X-User (ยังไม่มีระบบ login)
+func requestActor(c *gin.Context) string {
+	if u := strings.TrimSpace(c.GetHeader("X-User")); u != "" {
+		return u
+	}
+	return "admin"
+}
+
+// getModerationQueue รายการโพสต์ที่รอตรวจ
+func getModerationQueue(c *gin.Context) {
+	posts := make([]models.Post, 0)
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), bson.M{"status": models.PostStatusReview, "deleted_at": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if err := cursor.All(context.TODO(), &posts); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, withRelativeTime(posts, requestLang(c)))
+}
+
+// reviewPost อนุมัติ (publish) หรือปฏิเสธโพสต์ที่ถูก flag
+func reviewPost(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=approve reject"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	status := ""
+	switch req.Action {
+	case "approve":
+		status = models.PostStatusPublished
+	case "reject":
+		status = models.PostStatusRejected
+	default:
+		respondError(c, 400, errCodeValidationFailed, "action must be approve or reject")
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"status":                 status,
+		"moderation.reviewed_by": requestActor(c),
+		"moderation.reviewed_at": now,
+	}
+	if status == models.PostStatusPublished {
+		set["published_at"] = now
+	}
+	ensureBaselineRevision(id)
+	res, err := database.GetCollection("posts").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": id, "status": models.PostStatusReview, "deleted_at": bson.M{"$exists": false}},
+		touchPost(set, requestActor(c)),
+	)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		respondError(c, 404, errCodeNotFound, "Post not found in review queue")
+		return
+	}
+	_, _ = recordPostRevision(id, requestActor(c), "review: "+req.Action)
+	c.JSON(200, gin.H{"status": status})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModerateContentPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		flagged bool
+	}{
+		{"thai baht after number", "โซฟาตัวนี้ 500 บาท เท่านั้น", true},
+		{"thai baht at end", "500 บาท", true},
+		{"thai baht no space", "ลดเหลือ 1,290บาท", true},
+		{"price keyword", "ราคา 990 คุ้มมาก", true},
+		{"baht sign", "เพียง ฿1,500", true},
+		{"english baht", "Only 450 Baht today", true},
+		{"thb code", "from 2,000 THB", true},
+		{"thb inside word", "500 thbx", false},
+		{"number without currency", "จัดห้อง 3 มุมให้ดูกว้าง", false},
+		{"baht word without number", "ประหยัดไปหลายบาท", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := moderateContent(textGeneration{Content: tt.content})
+			got := false
+			for _, r := range m.Reasons {
+				if strings.HasPrefix(r, "price: ") {
+					got = true
+				}
+			}
+			if got != tt.flagged {
+				t.Errorf("price flagged = %v, want %v (reasons %v)", got, tt.flagged, m.Reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newJobID id ของการสร้างโพสต์หนึ่งครั้ง ใช้โยง post.generation กับ usage
+func newJobID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// applyTextProvenance บันทึก model, token, เวลา และผลความปลอดภัยของเนื้อหาที่ใช้จริง
+func applyTextProvenance(g *models.Generation, gen textGeneration) {
+	g.TextModel = gen.Model
+	g.FinishReason = gen.FinishReason
+	g.SafetyRatings = gen.SafetyRatings
+	g.PromptTokens = gen.PromptTokens
+	g.CandidateTokens = gen.CandidateTokens
+	g.TextLatencyMS = gen.Latency.Milliseconds()
+	g.GeneratedAt = time.Now()
+}
+
+// applyImageProvenance บันทึก model และเวลาที่ใช้สร้างรูป (err != nil คือใช้รูปสำรอง)
+func applyImageProvenance(g *models.Generation, latency time.Duration, err error) {
+	g.ImageModel = getImageModel()
+	g.ImageLatencyMS = latency.Milliseconds()
+	g.ImageError = ""
+	if err != nil {
+		g.ImageError = err.Error()
+	}
+	g.GeneratedAt = time.Now()
+}
+
+// generationStat ผลรวมของโพสต์ AI หนึ่งกลุ่ม
+type generationStat struct {
+	Key            any     `bson:"_id" json:"key"`
+	Posts          int64   `bson:"posts" json:"posts"`
+	AvgLikes       float64 `bson:"avg_likes" json:"avgLikes"`
+	AvgComments    float64 `bson:"avg_comments" json:"avgComments"`
+	Flagged        int64   `bson:"flagged" json:"flagged"`
+	AvgAttempts    float64 `bson:"avg_attempts" json:"avgAttempts"`
+	AvgLatencyMS   float64 `bson:"avg_latency_ms" json:"avgLatencyMs"`
+	AvgOutputToken float64 `bson:"avg_output_token" json:"avgOutputTokens"`
+}
+
+// getGenerationStats ผลลัพธ์ของโพสต์ AI จัดกลุ่มตาม ?groupBy=basePrompt|topic|textModel|wordLimit
+// ใช้ตอบว่า prompt ไหนได้ engagement ดีและโดน flag น้อย
+func getGenerationStats(c *gin.Context) {
+	groupField := map[string]string{
+		"basePrompt": "$generation.base_prompt",
+		"topic":      "$generation.topic",
+		"textModel":  "$generation.text_model",
+		"wordLimit":  "$generation.word_limit",
+	}
+	groupBy := c.DefaultQuery("groupBy", "basePrompt")
+	field, ok := groupField[groupBy]
+	if !ok {
+		respondError(c, 400, errCodeValidationFailed, "groupBy must be basePrompt, topic, textModel or wordLimit")
+		return
+	}
+
+	cursor, err := database.GetCollection("posts").Aggregate(context.TODO(), bson.A{
+		bson.M{"$match": bson.M{"generation": bson.M{"$exists": true}, "deleted_at": bson.M{"$exists": false}}},
+		bson.M{"$group": bson.M{
+			"_id":              field,
+			"posts":            bson.M{"$sum": 1},
+			"avg_likes":        bson.M{"$avg": "$like_count"},
+			"avg_comments":     bson.M{"$avg": "$comment_count"},
+			"flagged":          bson.M{"$sum": bson.M{"$cond": bson.A{"$moderation.flagged", 1, 0}}},
+			"avg_attempts":     bson.M{"$avg": "$generation.text_attempts"},
+			"avg_latency_ms":   bson.M{"$avg": "$generation.text_latency_ms"},
+			"avg_output_token": bson.M{"$avg": "$generation.candidate_tokens"},
+		}},
+		bson.M{"$sort": bson.D{{Key: "avg_likes", Value: -1}, {Key: "posts", Value: -1}}},
+	})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	rows := make([]generationStat, 0)
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"groupBy": groupBy, "rows": rows})
+}
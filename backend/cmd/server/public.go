@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	publicPageSize    = 20
+	publicMaxPageSize = 100
+	publicCacheHeader = "public, max-age=60, stale-while-revalidate=300"
+)
+
+// publicPost ข้อมูลโพสต์ที่เปิดให้ภายนอกเห็น (ไม่มีข้อมูล moderation/AI ภายใน)
+type publicPost struct {
+	ID          string      `json:"id"`
+	Slug        string      `json:"slug"`
+	Title       string      `json:"title"`
+	User        string      `json:"user"`
+	Content     string      `json:"content"`
+	Image       string      `json:"image"`
+	Likes       int64       `json:"likes"`
+	Comments    int64       `json:"comments"`
+	PublishedAt time.Time   `json:"publishedAt"`
+	Time        string      `json:"time"`
+	SEO         *models.SEO `json:"seo,omitempty"`
+	Tags        []string    `json:"tags"`
+	// ImageSet รูปย่อหลายขนาดสำหรับ srcset และ blurhash ระหว่างโหลด
+	ImageSet *models.ImageSet `json:"imageSet,omitempty"`
+}
+
+// publishedFilter โพสต์ที่เผยแพร่แล้ว (โพสต์เก่าที่ไม่มี status ถือว่าเผยแพร่)
+func publishedFilter() bson.M {
+	return bson.M{
+		"$or": bson.A{
+			bson.M{"status": models.PostStatusPublished},
+			bson.M{"status": bson.M{"$exists": false}},
+			bson.M{"status": ""},
+		},
+		"deleted_at": bson.M{"$exists": false},
+	}
+}
+
+// postPublishedAt เวลาที่เผยแพร่ ถ้าไม่มีใช้ CreatedAt
+func postPublishedAt(p models.Post) time.Time {
+	if p.PublishedAt != nil {
+		return *p.PublishedAt
+	}
+	return p.CreatedAt
+}
+
+func toPublicPost(p models.Post) publicPost {
+	slug := p.Slug
+	if slug == "" {
+		slug = p.ID.Hex()
+	}
+	return publicPost{
+		ID:          p.ID.Hex(),
+		Slug:        slug,
+		Title:       p.Title,
+		User:        p.User,
+		Content:     p.Content,
+		Image:       p.Image,
+		Likes:       p.Likes,
+		Comments:    p.Comments,
+		PublishedAt: postPublishedAt(p),
+		SEO:         p.SEO,
+		Tags:        append([]string{}, p.Tags...),
+		ImageSet:    p.ImageSet,
+	}
+}
+
+// serveCached ตอบพร้อม ETag/Last-Modified และคืน 304 ถ้า client มีข้อมูลล่าสุดแล้ว
+func serveCached(c *gin.Context, contentType string, body []byte, lastModified time.Time) {
+	sum := sha1.Sum(body)
+	etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", publicCacheHeader)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			if t := strings.TrimSpace(tag); t == etag || t == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	} else if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+func serveCachedJSON(c *gin.Context, payload any, lastModified time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	serveCached(c, "application/json; charset=utf-8", body, lastModified)
+}
+
+// getPublicPosts รายการโพสต์ที่เผยแพร่แล้ว แบ่งหน้าด้วย page/limit
+func getPublicPosts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(publicPageSize)))
+	if limit < 1 || limit > publicMaxPageSize {
+		limit = publicPageSize
+	}
+
+	coll := database.GetCollection("posts")
+	filter := publishedFilter()
+	if tag := normalizeTag(c.Query("tag")); tag != "" {
+		filter["tags"] = tag
+	}
+	total, err := coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	posts := make([]models.Post, 0)
+	_ = cursor.All(context.TODO(), &posts)
+
+	items := make([]publicPost, 0, len(posts))
+	var lastModified time.Time
+	for _, p := range withRelativeTime(posts, requestLang(c)) {
+		item := toPublicPost(p)
+		item.Time = p.Time
+		items = append(items, item)
+		if t := postLastModified(p); t.After(lastModified) {
+			lastModified = t
+		}
+	}
+
+	serveCachedJSON(c, gin.H{
+		"items": items,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	}, lastModified)
+}
+
+// getPublicPost ดึงโพสต์ด้วย slug (หรือ id สำหรับโพสต์ที่ยังไม่มี slug)
+func getPublicPost(c *gin.Context) {
+	slug := c.Param("slug")
+	match := bson.A{bson.M{"slug": slug}}
+	if id, err := primitive.ObjectIDFromHex(slug); err == nil {
+		match = append(match, bson.M{"_id": id})
+	}
+
+	var post models.Post
+	filter := bson.M{"$and": bson.A{publishedFilter(), bson.M{"$or": match}}}
+	if err := database.GetCollection("posts").FindOne(context.TODO(), filter).Decode(&post); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	item := toPublicPost(post)
+	item.Time = relativeTime(postPublishedAt(post), time.Now(), requestLang(c))
+	serveCachedJSON(c, item, postLastModified(post))
+}
+
+// postLastModified เวลาที่โพสต์เปลี่ยนล่าสุด ใช้กับ Last-Modified (เวลาแก้ไขหรือเวลาเผยแพร่ที่ใหม่กว่า)
+func postLastModified(p models.Post) time.Time {
+	published := postPublishedAt(p)
+	if p.UpdatedAt != nil && p.UpdatedAt.After(published) {
+		return *p.UpdatedAt
+	}
+	return published
+}
+
+// ctxAdminAuthenticated ตั้งใน gin context เมื่อ API key ผ่านการตรวจแล้ว
+const ctxAdminAuthenticated = "adminAuthenticated"
+
+// adminAuthMiddleware ล็อก /api ด้วย ADMIN_API_KEY (ถ้าไม่ได้ตั้งค่าจะเปิดไว้เหมือนเดิม)
+func adminAuthMiddleware() gin.HandlerFunc {
+	key := os.Getenv("ADMIN_API_KEY")
+	if key == "" {
+		log.Println("⚠️ ADMIN_API_KEY ไม่ได้ตั้งค่า /api จะเปิดให้เรียกได้โดยไม่ต้องยืนยันตัวตน")
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		got := c.GetHeader("X-API-Key")
+		if auth := c.GetHeader("Authorization"); got == "" && strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+			respondError(c, 401, errCodeUnauthorized, "Unauthorized")
+			return
+		}
+		c.Set(ctxAdminAuthenticated, true)
+		c.Next()
+	}
+}
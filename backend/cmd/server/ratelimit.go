@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// rateLimit token bucket: เติม Burst token ทุก Per
+type rateLimit struct {
+	Burst int
+	Per   time.Duration
+}
+
+func (l rateLimit) ratePerSecond() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// rateLimitStore เก็บสถานะ bucket, Take คืน allowed และเวลาที่ต้องรอถ้าไม่ผ่าน
+type rateLimitStore interface {
+	Take(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error)
+}
+
+// ค่าเริ่มต้นต่อกลุ่ม route (per client, global) override ได้ด้วย
+// RATE_LIMIT_<GROUP> และ RATE_LIMIT_<GROUP>_GLOBAL เช่น RATE_LIMIT_TEXT=10/1m
+var defaultRateLimits = map[string][2]rateLimit{
+	"text":       {{Burst: 10, Per: time.Minute}, {Burst: 60, Per: time.Minute}},
+	"image":      {{Burst: 5, Per: time.Minute}, {Burst: 20, Per: time.Minute}},
+	"trigger":    {{Burst: 2, Per: time.Minute}, {Burst: 10, Per: time.Minute}},
+	"engagement": {{Burst: 30, Per: time.Minute}, {Burst: 3000, Per: time.Minute}},
+	"comment":    {{Burst: 5, Per: 10 * time.Minute}, {Burst: 300, Per: time.Minute}},
+}
+
+var rateStore rateLimitStore
+
+func initRateLimiter() {
+	if os.Getenv("RATE_LIMIT_STORE") == "mongo" {
+		rateStore = newMongoRateLimitStore(database.GetCollection("rate_limits"))
+		log.Println("✅ Rate limit ใช้ Mongo store")
+		return
+	}
+	rateStore = newMemoryRateLimitStore()
+}
+
+// parseRateLimit แปลง "10/1m" เป็น rateLimit
+func parseRateLimit(v string) (rateLimit, error) {
+	parts := strings.SplitN(strings.TrimSpace(v), "/", 2)
+	if len(parts) != 2 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit %q", v)
+	}
+	burst, err := strconv.Atoi(parts[0])
+	if err != nil || burst <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit burst %q", v)
+	}
+	per, err := time.ParseDuration(parts[1])
+	if err != nil || per <= 0 {
+		return rateLimit{}, fmt.Errorf("invalid rate limit period %q", v)
+	}
+	return rateLimit{Burst: burst, Per: per}, nil
+}
+
+func getRateLimit(envKey string, fallback rateLimit) rateLimit {
+	v := os.Getenv(envKey)
+	if v == "" {
+		return fallback
+	}
+	l, err := parseRateLimit(v)
+	if err != nil {
+		log.Printf("⚠️ %s ไม่ถูกต้อง ใช้ค่าเริ่มต้นแทน: %v", envKey, err)
+		return fallback
+	}
+	return l
+}
+
+// rateLimitKey ระบุตัวผู้เรียก: แยกตาม X-User เฉพาะ request ที่ adminAuthMiddleware ตรวจ API key แล้ว
+// นอกนั้น (รวมทั้ง /public/v1) ใช้ IP เพราะ header ปลอมได้ทุก request
+func rateLimitKey(c *gin.Context) string {
+	if c.GetBool(ctxAdminAuthenticated) {
+		return "admin:" + requestActor(c)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimitMiddleware จำกัดการเรียกต่อ client และรวมทั้งระบบสำหรับกลุ่ม route
+func rateLimitMiddleware(group string) gin.HandlerFunc {
+	defaults, ok := defaultRateLimits[group]
+	if !ok {
+		panic("unknown rate limit group: " + group)
+	}
+	envKey := "RATE_LIMIT_" + strings.ToUpper(group)
+	perClient := getRateLimit(envKey, defaults[0])
+	global := getRateLimit(envKey+"_GLOBAL", defaults[1])
+
+	return func(c *gin.Context) {
+		checks := []struct {
+			key   string
+			limit rateLimit
+		}{
+			{key: group + ":" + rateLimitKey(c), limit: perClient},
+			{key: group + ":global", limit: global},
+		}
+
+		for _, check := range checks {
+			allowed, wait, err := rateStore.Take(c.Request.Context(), check.key, check.limit)
+			if err != nil {
+				// store ล่มไม่ควรทำให้ API ใช้ไม่ได้
+				log.Println("⚠️ Rate limit store error: ", err)
+				continue
+			}
+			if !allowed {
+				retry := int(math.Ceil(wait.Seconds()))
+				if retry < 1 {
+					retry = 1
+				}
+				c.Header("Retry-After", strconv.Itoa(retry))
+				respondError(c, 429, errCodeRateLimited, fmt.Sprintf("Too many requests, retry after %ds", retry))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// --- In-memory store (ใช้ได้เมื่อรัน instance เดียว) ---
+
+// memoryRateLimitSweepEvery ความถี่ในการล้าง bucket ที่เติมเต็มแล้ว
+const memoryRateLimitSweepEvery = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt เวลาที่ bucket จะเต็มอีกครั้ง หลังจากนั้นลบทิ้งได้โดยไม่เปลี่ยนผล
+	fullAt time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*memoryBucket), lastSweep: time.Now()}
+}
+
+// sweep ลบ bucket ที่ไม่ถูกใช้จนเต็มแล้ว กัน map โตตามจำนวน client (ต้องถือ mu อยู่)
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memoryRateLimitSweepEvery {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func (s *memoryRateLimitStore) Take(_ context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	rate := limit.ratePerSecond()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.fullAt = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / rate * float64(time.Second)))
+	if allowed {
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second)), nil
+}
+
+// --- Mongo store (ใช้เมื่อรันหลาย replica) ---
+
+type mongoRateLimitStore struct {
+	coll *mongo.Collection
+}
+
+func newMongoRateLimitStore(coll *mongo.Collection) *mongoRateLimitStore {
+	// ลบ bucket ที่ไม่ถูกใช้เกิน 1 ชั่วโมง
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "updated_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(3600),
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง TTL Index ของ rate_limits ได้: ", err)
+	}
+	return &mongoRateLimitStore{coll: coll}
+}
+
+// Take คำนวณและหัก token ใน update pipeline เดียวเพื่อให้ atomic ข้าม replica
+func (s *mongoRateLimitStore) Take(ctx context.Context, key string, limit rateLimit) (bool, time.Duration, error) {
+	now := time.Now()
+	rate := limit.ratePerSecond()
+	burst := float64(limit.Burst)
+
+	pipeline := bson.A{
+		bson.M{"$set": bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{
+					bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}, 1000}},
+					rate,
+				}},
+			}}}},
+			"updated_at": now,
+		}},
+		bson.M{"$set": bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}},
+		bson.M{"$set": bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}},
+	}
+
+	var out struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := s.coll.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&out); err != nil {
+		return false, 0, err
+	}
+	if out.Allowed {
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - out.Tokens) / rate * float64(time.Second)), nil
+}
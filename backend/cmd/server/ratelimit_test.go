@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitKeyIgnoresUnverifiedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/public/v1/posts/x/like", nil)
+	c.Request.RemoteAddr = "203.0.113.7:1234"
+	c.Request.Header.Set("X-User", "someone")
+	c.Request.Header.Set("X-API-Key", "random")
+
+	if got := rateLimitKey(c); got != "ip:203.0.113.7" {
+		t.Fatalf("unverified key = %q, want ip key", got)
+	}
+	c.Set(ctxAdminAuthenticated, true)
+	if got := rateLimitKey(c); got != "admin:someone" {
+		t.Fatalf("verified key = %q, want admin:someone", got)
+	}
+}
+
+func TestMemoryRateLimitStoreSweepsFullBuckets(t *testing.T) {
+	s := newMemoryRateLimitStore()
+	limit := rateLimit{Burst: 2, Per: time.Minute}
+	for _, key := range []string{"a", "b"} {
+		if ok, _, _ := s.Take(context.Background(), key, limit); !ok {
+			t.Fatalf("first take for %s was rejected", key)
+		}
+	}
+
+	// bucket "a" เต็มแล้ว ส่วน "b" ยังไม่เต็ม
+	now := time.Now()
+	s.buckets["a"].fullAt = now.Add(-time.Second)
+	s.lastSweep = now.Add(-2 * memoryRateLimitSweepEvery)
+	s.sweep(now)
+
+	if _, ok := s.buckets["a"]; ok {
+		t.Error("full bucket was not swept")
+	}
+	if _, ok := s.buckets["b"]; !ok {
+		t.Error("bucket that is still refilling was swept")
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	regeneratePartImage   = "image"
+	regeneratePartContent = "content"
+	regeneratePartBoth    = "both"
+)
+
+// generationInputs ค่าที่ใช้สร้างโพสต์ตอนแรก โพสต์ที่ไม่มีข้อมูลนี้ (เขียนเองหรือสร้างก่อนเก็บ metadata)
+// จะใช้ topic ของโพสต์และค่าจาก auto config แทน
+func generationInputs(post models.Post) models.Generation {
+	if post.Generation != nil {
+		gen := *post.Generation
+		if gen.WordLimit == 0 {
+			gen.WordLimit = getWordLimit()
+		}
+		if gen.ImagePrompt == "" {
+			gen.ImagePrompt = imagePromptFor(gen.Topic, gen.BasePrompt)
+		}
+		return gen
+	}
+
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	gen := models.Generation{
+		Topic:      post.Topic,
+		BasePrompt: config.BasePrompt,
+		WordLimit:  getWordLimit(),
+	}
+	if gen.Topic == "" {
+		gen.Topic = config.Topic
+	}
+	if gen.BasePrompt == "" {
+		gen.BasePrompt = pickRandomString(basePromptPool)
+	}
+	gen.ImagePrompt = imagePromptFor(gen.Topic, postTitle(post))
+	return gen
+}
+
+// regeneratePost สร้างรูปและ/หรือเนื้อหาใหม่ด้วย input เดิมของโพสต์ (part=image|content|both)
+// เนื้อหาเดิมยังอยู่ใน revision history
+func regeneratePost(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var req struct {
+		Part string `json:"part"`
+	}
+	if c.Request.ContentLength > 0 && !bindJSON(c, &req) {
+		return
+	}
+	if req.Part == "" {
+		req.Part = c.Query("part")
+	}
+	if req.Part != regeneratePartImage && req.Part != regeneratePartContent && req.Part != regeneratePartBoth {
+		respondError(c, 400, errCodeValidationFailed, "part must be image, content or both",
+			fieldError{Field: "part", Rule: "oneof", Param: "image content both", Message: "part must be image, content or both"})
+		return
+	}
+	if geminiClient == nil {
+		respondError(c, 500, errCodeAIUnavailable, "AI Client is not initialized")
+		return
+	}
+	version, conditional, ok := expectedVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	coll := database.GetCollection("posts")
+	var post models.Post
+	if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&post); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	if conditional && post.Version != version {
+		respondVersionConflict(c, post)
+		return
+	}
+
+	actor := requestActor(c)
+	inputs := generationInputs(post)
+	inputs.Source = usageProfileEditor
+	inputs.JobID = newJobID()
+	set := bson.M{}
+
+	if req.Part != regeneratePartImage {
+		gen, err := generateText(inputs.Topic, inputs.BasePrompt, inputs.WordLimit)
+		if err != nil {
+			log.Printf("❌ Gemini Content Error: %v", err)
+			respondError(c, 500, errCodeAIFailed, "Gemini failed: "+err.Error())
+			return
+		}
+		recordTextUsage(gen, usageProfileEditor, actor, inputs.JobID)
+		inputs.TextAttempts = 1
+
+		// กันเนื้อหาใหม่ซ้ำกับโพสต์อื่นเหมือน generateAIContent
+		var config models.AutoConfig
+		_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+		fingerprint := contentFingerprint(gen.Content)
+		dup, similarity := findNearDuplicate(fingerprint, config, id)
+		for attempt := 0; dup != nil && attempt < maxDuplicateRegenerations; attempt++ {
+			log.Printf("♻️ เนื้อหาใหม่ของโพสต์ %s ซ้ำกับโพสต์ %s (%.2f) กำลังสร้างใหม่", id.Hex(), dup.ID.Hex(), similarity)
+			inputs.BasePrompt = pickRandomString(basePromptPool)
+			retry, err := generateText(inputs.Topic, inputs.BasePrompt, inputs.WordLimit)
+			if err != nil {
+				log.Println("❌ Gemini Content Error: ", err)
+				break
+			}
+			recordTextUsage(retry, usageProfileEditor, actor, inputs.JobID)
+			inputs.TextAttempts++
+			gen = retry
+			fingerprint = contentFingerprint(gen.Content)
+			dup, similarity = findNearDuplicate(fingerprint, config, id)
+		}
+
+		applyTextProvenance(&inputs, gen)
+		moderation := moderateContent(gen)
+		if dup != nil {
+			moderation.Reasons = append(moderation.Reasons, fmt.Sprintf("near_duplicate: %s (%.2f)", dup.ID.Hex(), similarity))
+			moderation.Flagged = true
+		}
+		set["content"] = gen.Content
+		set["fingerprint"] = fingerprint
+		set["moderation"] = moderation
+		// เนื้อหาใหม่ที่ติดกฎต้องผ่าน review ก่อนเผยแพร่เหมือนโพสต์ที่สร้างอัตโนมัติ
+		if moderation.Flagged && post.Status != models.PostStatusArchived {
+			set["status"] = models.PostStatusReview
+		}
+	}
+	if req.Part != regeneratePartContent {
+		started := time.Now()
+		image, err := generateImageOnly(inputs.ImagePrompt)
+		applyImageProvenance(&inputs, time.Since(started), err)
+		if err != nil {
+			log.Printf("❌ Gemini Image Error: %v", err)
+			respondError(c, 500, errCodeAIFailed, "Gemini image failed: "+err.Error())
+			return
+		}
+		recordImageUsage(usageProfileEditor, actor, inputs.JobID)
+		image = watermarkImage(image, usageProfileEditor)
+		for k, v := range imageFields(image, mediaSourceGenerated, id) {
+			set[k] = v
+		}
+	}
+	set["generation"] = inputs
+
+	ensureBaselineRevision(id)
+	res, err := coll.UpdateOne(context.TODO(), versionFilter(id, post.Version), touchPost(set, actor))
+	if err != nil {
+		discardStoredImage(set)
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		discardStoredImage(set)
+		// มีคนแก้โพสต์ระหว่างรอ AI ไม่เขียนทับ ให้ client ตัดสินใจเอง
+		var current models.Post
+		if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&current); err != nil {
+			respondError(c, 404, errCodeNotFound, "Post not found")
+			return
+		}
+		respondVersionConflict(c, current)
+		return
+	}
+	refreshPostSEO(id)
+	autoDescribeImageSet(storedImageSet(set), usageBilling{Profile: usageProfileEditor, User: actor, JobID: inputs.JobID})
+
+	updated, err := recordPostRevision(id, actor, "regenerate "+req.Part)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	updated.Time = relativeTime(postPublishedAt(updated), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(updated.Version))
+	c.JSON(200, updated)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// เวลาประเทศไทย (ไม่ใช้ LoadLocation เพราะ image อาจไม่มี tzdata)
+var bangkokTZ = time.FixedZone("ICT", 7*60*60)
+
+var thaiMonthsShort = []string{"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.", "ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค."}
+
+// requestLang ภาษาของผลลัพธ์จาก ?lang= หรือ Accept-Language (ค่าเริ่มต้นภาษาไทย)
+func requestLang(c *gin.Context) string {
+	lang := strings.ToLower(c.Query("lang"))
+	if lang == "" {
+		lang = strings.ToLower(c.GetHeader("Accept-Language"))
+	}
+	if strings.HasPrefix(lang, "en") {
+		return "en"
+	}
+	return "th"
+}
+
+// relativeTime แสดงเวลาแบบ "5 นาทีที่แล้ว" / "5 minutes ago" เกิน 7 วันแสดงเป็นวันที่
+// (ภาษาไทยใช้ปีพุทธศักราช)
+func relativeTime(t, now time.Time, lang string) string {
+	d := now.Sub(t)
+	if d < 0 {
+		d = 0
+	}
+
+	switch {
+	case d < time.Minute:
+		if lang == "en" {
+			return "just now"
+		}
+		return "เมื่อสักครู่"
+	case d < time.Hour:
+		n := int(d / time.Minute)
+		if lang == "en" {
+			return plural(n, "minute") + " ago"
+		}
+		return fmt.Sprintf("%d นาทีที่แล้ว", n)
+	case d < 24*time.Hour:
+		n := int(d / time.Hour)
+		if lang == "en" {
+			return plural(n, "hour") + " ago"
+		}
+		return fmt.Sprintf("%d ชั่วโมงที่แล้ว", n)
+	case d < 7*24*time.Hour:
+		n := int(d / (24 * time.Hour))
+		if n == 1 {
+			if lang == "en" {
+				return "yesterday"
+			}
+			return "เมื่อวาน"
+		}
+		if lang == "en" {
+			return plural(n, "day") + " ago"
+		}
+		return fmt.Sprintf("%d วันที่แล้ว", n)
+	}
+
+	local := t.In(bangkokTZ)
+	if lang == "en" {
+		return local.Format("Jan 2, 2006")
+	}
+	return fmt.Sprintf("%d %s %d", local.Day(), thaiMonthsShort[local.Month()-1], local.Year()+543)
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// withRelativeTime เติม Time (คำนวณตอนอ่าน) ให้รายการโพสต์
+func withRelativeTime(posts []models.Post, lang string) []models.Post {
+	now := time.Now()
+	for i := range posts {
+		posts[i].Time = relativeTime(postPublishedAt(posts[i]), now, lang)
+	}
+	return posts
+}
+
+// unsetStoredTime ลบฟิลด์ time ที่เคยเก็บเป็นข้อความตายตัว (เช่น "เมื่อสักครู่") ออกจากโพสต์เก่า
+func unsetStoredTime() {
+	res, err := database.GetCollection("posts").UpdateMany(context.TODO(),
+		bson.M{"time": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"time": ""}})
+	if err != nil {
+		log.Println("⚠️ ลบฟิลด์ time เดิมไม่สำเร็จ: ", err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("🕒 ลบฟิลด์ time เดิมออกจาก %d โพสต์", res.ModifiedCount)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// createPostRequest ฟิลด์ที่ client ส่งมาสร้างโพสต์ได้ (likes/comments/time จะถูกละไว้)
+// image อาจเป็น data URL จาก Imagen จึงยอมให้ยาวได้ถึง 15MB
+type createPostRequest struct {
+	User        string   `json:"user" binding:"required,max=100"`
+	Content     string   `json:"content" binding:"required,max=20000"`
+	Image       string   `json:"image" binding:"omitempty,max=15728640"`
+	Title       string   `json:"title" binding:"omitempty,max=200"`
+	Status      string   `json:"status" binding:"omitempty,oneof=published review"`
+	Tags        []string `json:"tags" binding:"max=10,dive,max=40"`
+	CategoryIDs []string `json:"categoryIds" binding:"max=20,dive,mongodb"`
+}
+
+// updatePostRequest whitelist ของฟิลด์ที่แก้ไขได้ ฟิลด์ที่เป็น nil จะไม่ถูกแตะ
+type updatePostRequest struct {
+	User        *string   `json:"user" binding:"omitempty,min=1,max=100"`
+	Content     *string   `json:"content" binding:"omitempty,min=1,max=20000"`
+	Image       *string   `json:"image" binding:"omitempty,max=15728640"`
+	Title       *string   `json:"title" binding:"omitempty,max=200"`
+	Slug        *string   `json:"slug" binding:"omitempty,min=1,max=80"`
+	Status      *string   `json:"status" binding:"omitempty,oneof=published review rejected archived"`
+	Tags        *[]string `json:"tags" binding:"omitempty,max=10,dive,max=40"`
+	CategoryIDs *[]string `json:"categoryIds" binding:"omitempty,max=20,dive,mongodb"`
+	// Version ใช้แทน If-Match ได้สำหรับ client ที่ตั้ง header ไม่สะดวก
+	Version *int64 `json:"version" binding:"omitempty,min=0"`
+	// Reason เหตุผลของการแก้ไข เก็บไว้ใน revision
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// toSet แปลงเป็น $set ของ Mongo คืน fieldError ถ้า slug ไม่ถูกรูปแบบหรือหมวดหมู่ไม่มีอยู่จริง
+func (r updatePostRequest) toSet() (bson.M, []fieldError) {
+	set := bson.M{}
+	if r.User != nil {
+		set["user"] = strings.TrimSpace(*r.User)
+	}
+	if r.Content != nil {
+		set["content"] = *r.Content
+		set["fingerprint"] = contentFingerprint(*r.Content)
+	}
+	if r.Image != nil {
+		set["image"] = strings.TrimSpace(*r.Image)
+	}
+	if r.Title != nil {
+		set["title"] = strings.TrimSpace(*r.Title)
+	}
+	if r.Slug != nil {
+		if !slugPattern.MatchString(*r.Slug) {
+			return nil, []fieldError{{Field: "slug", Rule: "slug", Message: "slug must contain only a-z, 0-9 and single hyphens"}}
+		}
+		set["slug"] = *r.Slug
+	}
+	if r.Status != nil {
+		set["status"] = *r.Status
+	}
+	if r.Tags != nil {
+		set["tags"] = normalizeTags(*r.Tags)
+	}
+	if r.CategoryIDs != nil {
+		ids, errs := resolveCategoryIDs(*r.CategoryIDs)
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		set["category_ids"] = ids
+	}
+	return set, nil
+}
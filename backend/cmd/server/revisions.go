@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	revisionReasonCreated   = "created"
+	revisionReasonGenerated = "generated"
+	revisionReasonBaseline  = "baseline"
+	revisionReasonEdit      = "edit"
+	// diff แบบ LCS ใช้หน่วยความจำ O(n*m) จึงจำกัดจำนวนบรรทัด
+	maxDiffLines = 2000
+)
+
+// diffLine บรรทัดหนึ่งใน line diff: op เป็น equal, insert หรือ delete
+type diffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+func ensureRevisionIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("post_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "version", Value: -1}},
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index ของ post_revisions ได้: ", err)
+	}
+}
+
+// recordRevision เก็บสำเนาของโพสต์ ณ ตอนนี้เป็น revision ใหม่
+func recordRevision(post models.Post, actor, reason string) {
+	post.Time = ""
+	rev := models.PostRevision{
+		ID:        primitive.NewObjectID(),
+		PostID:    post.ID,
+		Version:   post.Version,
+		Snapshot:  post,
+		Actor:     actor,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	}
+	if _, err := database.GetCollection("post_revisions").InsertOne(context.TODO(), rev); err != nil {
+		log.Println("⚠️ บันทึก revision ไม่สำเร็จ: ", err)
+	}
+}
+
+// recordPostRevision โหลดโพสต์ล่าสุดแล้วเก็บเป็น revision คืนโพสต์ที่โหลดมา
+func recordPostRevision(id primitive.ObjectID, actor, reason string) (models.Post, error) {
+	var post models.Post
+	if err := database.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&post); err != nil {
+		return post, err
+	}
+	recordRevision(post, actor, reason)
+	return post, nil
+}
+
+// ensureBaselineRevision โพสต์ที่สร้างก่อนมีระบบ revision จะถูกเก็บสภาพเดิมไว้ก่อนแก้ไขครั้งแรก
+func ensureBaselineRevision(id primitive.ObjectID) {
+	n, err := database.GetCollection("post_revisions").CountDocuments(context.TODO(), bson.M{"post_id": id}, options.Count().SetLimit(1))
+	if err != nil || n > 0 {
+		return
+	}
+	var post models.Post
+	if err := database.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&post); err != nil {
+		return
+	}
+	actor := post.UpdatedBy
+	if actor == "" {
+		actor = post.User
+	}
+	recordRevision(post, actor, revisionReasonBaseline)
+}
+
+// lineDiff เทียบสองข้อความทีละบรรทัดด้วย longest common subsequence
+func lineDiff(a, b string) []diffLine {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// lcs[i][j] = ความยาว LCS ของ x[i:] กับ y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	out := make([]diffLine, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			out = append(out, diffLine{Op: "equal", Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{Op: "delete", Text: x[i]})
+			i++
+		default:
+			out = append(out, diffLine{Op: "insert", Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		out = append(out, diffLine{Op: "delete", Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		out = append(out, diffLine{Op: "insert", Text: y[j]})
+	}
+	return out
+}
+
+func findRevision(postID primitive.ObjectID, hex string) (models.PostRevision, bool) {
+	var rev models.PostRevision
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return rev, false
+	}
+	err = database.GetCollection("post_revisions").FindOne(context.TODO(), bson.M{"_id": id, "post_id": postID}).Decode(&rev)
+	return rev, err == nil
+}
+
+// getRevisions รายการ revision ของโพสต์ (ไม่รวมเนื้อหาเต็ม) ใหม่สุดก่อน
+func getRevisions(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}, {Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"snapshot.content": 0, "snapshot.image": 0, "snapshot.variants": 0, "snapshot.seo": 0})
+	cursor, err := database.GetCollection("post_revisions").Find(context.TODO(), bson.M{"post_id": id}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	revs := make([]models.PostRevision, 0)
+	_ = cursor.All(context.TODO(), &revs)
+	c.JSON(200, revs)
+}
+
+func getRevision(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	rev, found := findRevision(id, c.Param("rev"))
+	if !found {
+		respondError(c, 404, errCodeNotFound, "Revision not found")
+		return
+	}
+	c.JSON(200, rev)
+}
+
+// getRevisionDiff line diff ของ content ระหว่าง ?from= และ ?to= (ถ้าไม่ระบุ to จะเทียบกับโพสต์ปัจจุบัน)
+func getRevisionDiff(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	from, found := findRevision(id, c.Query("from"))
+	if !found {
+		respondError(c, 404, errCodeNotFound, "Revision 'from' not found")
+		return
+	}
+
+	var to models.Post
+	toLabel := "current"
+	if v := c.Query("to"); v != "" {
+		rev, found := findRevision(id, v)
+		if !found {
+			respondError(c, 404, errCodeNotFound, "Revision 'to' not found")
+			return
+		}
+		to = rev.Snapshot
+		toLabel = rev.ID.Hex()
+	} else if err := database.GetCollection("posts").FindOne(context.TODO(), bson.M{"_id": id}).Decode(&to); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	if strings.Count(from.Snapshot.Content, "\n") >= maxDiffLines || strings.Count(to.Content, "\n") >= maxDiffLines {
+		respondError(c, 400, errCodeValidationFailed, "Content is too long to diff")
+		return
+	}
+
+	changed := make([]string, 0)
+	if from.Snapshot.Title != to.Title {
+		changed = append(changed, "title")
+	}
+	if from.Snapshot.Image != to.Image {
+		changed = append(changed, "image")
+	}
+	if from.Snapshot.User != to.User {
+		changed = append(changed, "user")
+	}
+	if from.Snapshot.Status != to.Status {
+		changed = append(changed, "status")
+	}
+
+	c.JSON(200, gin.H{
+		"from":          from.ID.Hex(),
+		"to":            toLabel,
+		"fromVersion":   from.Version,
+		"toVersion":     to.Version,
+		"changedFields": changed,
+		"lines":         lineDiff(from.Snapshot.Content, to.Content),
+	})
+}
+
+// restoreRevision นำเนื้อหาของ revision เก่ากลับมาเป็นเวอร์ชันใหม่ (ไม่ลบประวัติระหว่างทาง)
+func restoreRevision(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	rev, found := findRevision(id, c.Param("rev"))
+	if !found {
+		respondError(c, 404, errCodeNotFound, "Revision not found")
+		return
+	}
+	version, conditional, ok := expectedVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	filter := activePostFilter(id)
+	if conditional {
+		filter = versionFilter(id, version)
+	}
+	actor := requestActor(c)
+	ensureBaselineRevision(id)
+	coll := database.GetCollection("posts")
+	set := imageFields(rev.Snapshot.Image, mediaSourceUpload, id)
+	set["user"] = rev.Snapshot.User
+	set["content"] = rev.Snapshot.Content
+	set["title"] = rev.Snapshot.Title
+	set["tags"] = rev.Snapshot.Tags
+	set["category_ids"] = rev.Snapshot.CategoryIDs
+	set["fingerprint"] = contentFingerprint(rev.Snapshot.Content)
+	res, err := coll.UpdateOne(context.TODO(), filter, touchPost(set, actor))
+	if err != nil {
+		discardStoredImage(set)
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		discardStoredImage(set)
+		var current models.Post
+		if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&current); err != nil {
+			respondError(c, 404, errCodeNotFound, "Post not found")
+			return
+		}
+		respondVersionConflict(c, current)
+		return
+	}
+	refreshPostSEO(id)
+	autoDescribeImageSet(storedImageSet(set), usageBilling{Profile: usageProfileEditor, User: actor})
+
+	post, err := recordPostRevision(id, actor, fmt.Sprintf("restored from version %d", rev.Version))
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	post.Time = relativeTime(postPublishedAt(post), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(post.Version))
+	c.JSON(200, post)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	searchPageSize     = 20
+	searchMaxPageSize  = 100
+	searchSnippetRunes = 80
+	// searchTokenVersion เพิ่มเมื่อวิธีตัดคำเปลี่ยน เพื่อให้ backfillSearchIndex ตัดคำโพสต์เดิมใหม่
+	searchTokenVersion = 2
+)
+
+// searchHit ผลค้นหาหนึ่งรายการ highlight เป็น HTML ที่ escape แล้วและครอบคำที่ตรงด้วย <mark>
+type searchHit struct {
+	Post      models.Post       `json:"post"`
+	Score     float64           `json:"score"`
+	Highlight map[string]string `json:"highlight"`
+}
+
+// ensureSearchIndex text index บนฟิลด์ที่ตัดคำแล้ว ใช้ language none เพราะ Mongo ไม่มี stemmer ภาษาไทย
+func ensureSearchIndex() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("posts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "search_title", Value: "text"}, {Key: "search_body", Value: "text"}},
+		Options: options.Index().
+			SetName("post_search").
+			SetDefaultLanguage("none").
+			SetWeights(bson.M{"search_title": 5, "search_body": 1}),
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง text index ของ posts ได้: ", err)
+	}
+}
+
+// applySearchTokens ตัดคำ title/content เก็บลงฟิลด์สำหรับ text index
+func applySearchTokens(p *models.Post) {
+	p.SearchTitle = strings.Join(indexTokens(p.Title), " ")
+	p.SearchBody = strings.Join(indexTokens(p.Content), " ")
+	p.SearchVersion = searchTokenVersion
+}
+
+// backfillSearchIndex ตัดคำให้โพสต์ที่สร้างก่อนมีระบบค้นหา หรือที่ตัดคำด้วยพจนานุกรมรุ่นเก่า
+func backfillSearchIndex() {
+	cursor, err := database.GetCollection("posts").Find(context.TODO(),
+		bson.M{"search_version": bson.M{"$ne": searchTokenVersion}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return
+	}
+	var ids []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	_ = cursor.All(context.TODO(), &ids)
+	for _, row := range ids {
+		refreshPostSEO(row.ID)
+	}
+	if len(ids) > 0 {
+		log.Printf("🔎 ตัดคำสำหรับค้นหาให้โพสต์เก่า %d โพสต์", len(ids))
+	}
+}
+
+// postListQuery เงื่อนไขกรองโพสต์ที่ใช้ร่วมกันระหว่างรายการโพสต์ การค้นหา และ bulk job
+type postListQuery struct {
+	Status   string   `json:"status"`
+	Topic    string   `json:"topic"`
+	User     string   `json:"user"`
+	Tags     []string `json:"tags"`
+	Category string   `json:"category"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+}
+
+// filter แปลงเป็น filter ของ Mongo (ไม่รวมโพสต์ในถังขยะเสมอ)
+// คืน fieldError เมื่อค่าที่ส่งมาไม่ถูกต้อง และ error เมื่อโหลดหมวดหมู่ไม่สำเร็จ
+func (q postListQuery) filter() (bson.M, *fieldError, error) {
+	and := bson.A{bson.M{"deleted_at": bson.M{"$exists": false}}}
+
+	switch q.Status {
+	case "":
+	case models.PostStatusPublished:
+		and = append(and, publishedFilter())
+	case models.PostStatusReview, models.PostStatusRejected, models.PostStatusArchived:
+		and = append(and, bson.M{"status": q.Status})
+	default:
+		return nil, &fieldError{Field: "status", Rule: "oneof", Message: "status must be published, review, rejected or archived"}, nil
+	}
+	if v := strings.TrimSpace(q.Topic); v != "" {
+		and = append(and, bson.M{"topic": v})
+	}
+	if v := strings.TrimSpace(q.User); v != "" {
+		and = append(and, bson.M{"user": v})
+	}
+	if tags := normalizeTags(q.Tags); len(tags) > 0 {
+		and = append(and, bson.M{"tags": bson.M{"$all": tags}})
+	}
+	if q.Category != "" {
+		id, err := primitive.ObjectIDFromHex(q.Category)
+		if err != nil {
+			return nil, &fieldError{Field: "category", Rule: "mongodb", Message: "Invalid category"}, nil
+		}
+		cats, err := loadCategories()
+		if err != nil {
+			return nil, nil, err
+		}
+		and = append(and, bson.M{"category_ids": bson.M{"$in": categoryDescendants(cats, id)}})
+	}
+
+	created := bson.M{}
+	if q.From != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.From, bangkokTZ)
+		if err != nil {
+			return nil, &fieldError{Field: "from", Rule: "datetime", Message: "from must be YYYY-MM-DD"}, nil
+		}
+		created["$gte"] = t
+	}
+	if q.To != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.To, bangkokTZ)
+		if err != nil {
+			return nil, &fieldError{Field: "to", Rule: "datetime", Message: "to must be YYYY-MM-DD"}, nil
+		}
+		created["$lt"] = t.AddDate(0, 0, 1)
+	}
+	if len(created) > 0 {
+		and = append(and, bson.M{"created_at": created})
+	}
+	return bson.M{"$and": and}, nil, nil
+}
+
+// postListFilter อ่าน postListQuery จาก query string:
+// ?status=published|review|rejected|archived, ?topic=, ?user=, ?tag= (ซ้ำได้ ต้องมีครบทุก tag),
+// ?category= (รวมหมวดย่อย), ?from=YYYY-MM-DD, ?to=YYYY-MM-DD
+func postListFilter(c *gin.Context) (bson.M, bool) {
+	q := postListQuery{
+		Status:   c.Query("status"),
+		Topic:    c.Query("topic"),
+		User:     c.Query("user"),
+		Tags:     c.QueryArray("tag"),
+		Category: c.Query("category"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+	}
+	filter, ferr, err := q.filter()
+	if ferr != nil {
+		respondError(c, 400, errCodeValidationFailed, ferr.Message, *ferr)
+		return nil, false
+	}
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return nil, false
+	}
+	return filter, true
+}
+
+// highlightText ตัดช่วงข้อความรอบคำที่ตรงครั้งแรก แล้วครอบทุกคำที่ตรงด้วย <mark>
+// คืนค่าว่างถ้าไม่มีคำไหนตรง
+func highlightText(text string, terms []string, window int) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// ตัวพิมพ์เล็กบางตัวเปลี่ยนจำนวน rune ใช้ข้อความเดิมเทียบแทน
+		lower = runes
+	}
+	// term จาก tokenizeText ใช้เลขอารบิก จึงต้องแปลงเลขไทยในข้อความให้ตรงกันก่อนเทียบ
+	lower = append([]rune(nil), lower...)
+	for i, r := range lower {
+		if d, ok := thaiDigits[r]; ok {
+			lower[i] = d
+		}
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			for k := i; k < i+len(t); k++ {
+				marked[k] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	start, end := 0, len(runes)
+	if window > 0 {
+		start = first - window/2
+		if start < 0 {
+			start = 0
+		}
+		end = start + window
+		if end > len(runes) {
+			end = len(runes)
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	open := false
+	for i := start; i < end; i++ {
+		if marked[i] != open {
+			if marked[i] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			open = marked[i]
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// searchPosts ค้นหาโพสต์ด้วย ?q= เรียงตามความเกี่ยวข้อง รองรับ filter เดียวกับรายการโพสต์
+func searchPosts(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" || utf8.RuneCountInString(q) > 200 {
+		respondError(c, 400, errCodeValidationFailed, "q is required (max 200 characters)")
+		return
+	}
+	seen := make(map[string]bool)
+	terms := make([]string, 0)
+	for _, t := range tokenizeText(q) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	if len(terms) == 0 {
+		respondError(c, 400, errCodeValidationFailed, "q has no searchable words")
+		return
+	}
+
+	filter, ok := postListFilter(c)
+	if !ok {
+		return
+	}
+	filter["$text"] = bson.M{"$search": strings.Join(terms, " ")}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(searchPageSize)))
+	if limit < 1 || limit > searchMaxPageSize {
+		limit = searchPageSize
+	}
+
+	coll := database.GetCollection("posts")
+	total, err := coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score, "variants": 0}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	var rows []struct {
+		models.Post `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	lang := requestLang(c)
+	now := time.Now()
+	hits := make([]searchHit, 0, len(rows))
+	for _, row := range rows {
+		p := row.Post
+		p.Time = relativeTime(postPublishedAt(p), now, lang)
+		hit := searchHit{Post: p, Score: row.Score, Highlight: map[string]string{}}
+		if h := highlightText(p.Title, terms, 0); h != "" {
+			hit.Highlight["title"] = h
+		}
+		if h := highlightText(p.Content, terms, searchSnippetRunes*2); h != "" {
+			hit.Highlight["content"] = h
+		}
+		hits = append(hits, hit)
+	}
+
+	c.JSON(200, gin.H{
+		"query": q,
+		"terms": terms,
+		"items": hits,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
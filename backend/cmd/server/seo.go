@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	slugMaxLength        = 60
+	slugInsertAttempts   = 5
+	postSlugIndex        = "post_slug"
+	metaDescriptionRunes = 155
+	sitemapPageSize      = 1000
+)
+
+// ถอดเสียงพยัญชนะไทยเป็นอักษรโรมันแบบง่าย (อิงหลัก RTGS เสียงต้น)
+var thaiConsonants = map[rune]string{
+	'ก': "k", 'ข': "kh", 'ฃ': "kh", 'ค': "kh", 'ฅ': "kh", 'ฆ': "kh", 'ง': "ng",
+	'จ': "ch", 'ฉ': "ch", 'ช': "ch", 'ซ': "s", 'ฌ': "ch", 'ญ': "y",
+	'ฎ': "d", 'ฏ': "t", 'ฐ': "th", 'ฑ': "th", 'ฒ': "th", 'ณ': "n",
+	'ด': "d", 'ต': "t", 'ถ': "th", 'ท': "th", 'ธ': "th", 'น': "n",
+	'บ': "b", 'ป': "p", 'ผ': "ph", 'ฝ': "f", 'พ': "ph", 'ฟ': "f", 'ภ': "ph", 'ม': "m",
+	'ย': "y", 'ร': "r", 'ฤ': "rue", 'ล': "l", 'ฦ': "lue", 'ว': "w",
+	'ศ': "s", 'ษ': "s", 'ส': "s", 'ห': "h", 'ฬ': "l", 'อ': "", 'ฮ': "h",
+}
+
+var thaiVowels = map[rune]string{
+	'ะ': "a", 'ั': "a", 'า': "a", 'ำ': "am", 'ิ': "i", 'ี': "i", 'ึ': "ue", 'ื': "ue",
+	'ุ': "u", 'ู': "u", 'เ': "e", 'แ': "ae", 'โ': "o", 'ใ': "ai", 'ไ': "ai", 'ๅ': "",
+}
+
+// สระหน้าที่เขียนก่อนพยัญชนะแต่ออกเสียงหลังพยัญชนะ
+var thaiLeadingVowels = map[rune]bool{'เ': true, 'แ': true, 'โ': true, 'ใ': true, 'ไ': true}
+
+var thaiDigits = map[rune]rune{'๐': '0', '๑': '1', '๒': '2', '๓': '3', '๔': '4', '๕': '5', '๖': '6', '๗': '7', '๘': '8', '๙': '9'}
+
+// transliterateThai แปลงข้อความไทยเป็นอักษรโรมันคร่าวๆ เพื่อใช้ทำ slug
+func transliterateThai(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	pending := ""
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		// การันต์: ตัดพยัญชนะที่อยู่ก่อนหน้าทิ้ง จึงข้ามตั้งแต่ตอนเจอพยัญชนะ
+		if i+1 < len(runes) && runes[i+1] == '์' {
+			i++
+			continue
+		}
+		switch {
+		case thaiLeadingVowels[r]:
+			pending = thaiVowels[r]
+		case thaiConsonants[r] != "" || r == 'อ':
+			b.WriteString(thaiConsonants[r])
+			if pending != "" {
+				b.WriteString(pending)
+				pending = ""
+			} else if r == 'อ' && (i+1 >= len(runes) || thaiConsonants[runes[i+1]] != "") {
+				b.WriteString("o")
+			}
+		case thaiVowels[r] != "":
+			b.WriteString(thaiVowels[r])
+		case thaiDigits[r] != 0:
+			b.WriteRune(thaiDigits[r])
+		case r >= 0x0E00 && r <= 0x0E7F:
+			// วรรณยุกต์และเครื่องหมายอื่นไม่มีผลกับ slug
+		default:
+			b.WriteString(pending)
+			pending = ""
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(pending)
+	return b.String()
+}
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify สร้าง slug ภาษาอังกฤษจากข้อความ (คืนค่าว่างถ้าถอดเสียงไม่ได้)
+// ข้อความไทยถูกตัดคำก่อนถอดเสียง แต่ละคำจึงคั่นด้วย "-" เช่น "ห้องนอนสไตล์มินิมอล" → hongnon-stai-minimol
+func slugify(s string) string {
+	s = strings.ToLower(transliterateThai(thaiWordBreaks(s)))
+	s = strings.Trim(slugSeparators.ReplaceAllString(s, "-"), "-")
+	if len(s) > slugMaxLength {
+		s = strings.TrimRight(s[:slugMaxLength], "-")
+		if i := strings.LastIndexByte(s, '-'); i > slugMaxLength/2 {
+			s = s[:i]
+		}
+	}
+	return s
+}
+
+// uniqueSlug เติม id ท้าย slug ถ้าซ้ำกับโพสต์อื่น หรือใช้ id ถ้า slug ว่าง
+func uniqueSlug(base string, id primitive.ObjectID) string {
+	if base == "" {
+		return id.Hex()
+	}
+	n, err := database.GetCollection("posts").CountDocuments(context.TODO(), bson.M{"slug": base, "_id": bson.M{"$ne": id}})
+	if err == nil && n == 0 {
+		return base
+	}
+	hex := id.Hex()
+	return base + "-" + hex[len(hex)-6:]
+}
+
+// ensurePostSlugIndex slug ของโพสต์ต้องไม่ซ้ำ (โพสต์เก่าที่ยังไม่มี slug ไม่นับ)
+func ensurePostSlugIndex() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("posts").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().
+			SetName(postSlugIndex).
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"slug": bson.M{"$type": "string"}}),
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง unique index ของ slug ได้: ", err)
+	}
+}
+
+// insertPost บันทึกโพสต์ใหม่ uniqueSlug เช็คก่อนบันทึกจึงชนกับคำขอที่สร้าง slug เดียวกันพร้อมกันได้
+// ถ้าชน unique index ของ slug จะสุ่ม suffix ใหม่แล้วลองอีกครั้ง error อื่น (รวมถึง _id ซ้ำ) คืนตามเดิม
+func insertPost(p *models.Post) error {
+	base := p.Slug
+	hex := p.ID.Hex()
+	base = strings.TrimSuffix(base, "-"+hex[len(hex)-6:])
+	coll := database.GetCollection("posts")
+	for attempt := 1; ; attempt++ {
+		_, err := coll.InsertOne(context.TODO(), p)
+		if err == nil || !isSlugConflict(err) || attempt >= slugInsertAttempts {
+			return err
+		}
+		p.Slug = fmt.Sprintf("%s-%06x", base, rand.Intn(1<<24))
+		if p.SEO != nil {
+			p.SEO.CanonicalURL = postURL(*p)
+		}
+	}
+}
+
+// isSlugConflict error จาก unique index ของ slug
+func isSlugConflict(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), postSlugIndex)
+}
+
+var markdownNoise = regexp.MustCompile("[#*_`>~\\[\\]]+")
+
+// metaDescription ตัดเนื้อหาเป็นคำอธิบายสั้นไม่เกิน metaDescriptionRunes ตัวอักษร
+func metaDescription(content string) string {
+	s := strings.Join(strings.Fields(markdownNoise.ReplaceAllString(content, " ")), " ")
+	if utf8.RuneCountInString(s) <= metaDescriptionRunes {
+		return s
+	}
+	r := []rune(s)[:metaDescriptionRunes-1]
+	// ตัดที่ช่องว่างถ้าทำได้ เพื่อไม่ให้คำขาดกลาง
+	for i := len(r) - 1; i > metaDescriptionRunes/2; i-- {
+		if unicode.IsSpace(r[i]) {
+			r = r[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(string(r)) + "…"
+}
+
+// applySEO เติม title, slug, meta (OG/Twitter) และ token สำหรับค้นหา slug ที่มีอยู่แล้วจะไม่ถูกเปลี่ยน
+func applySEO(p *models.Post) {
+	if strings.TrimSpace(p.Title) == "" {
+		p.Title = postTitle(*p)
+	}
+	if p.Slug == "" {
+		p.Slug = uniqueSlug(slugify(p.Title), p.ID)
+	}
+
+	description := metaDescription(p.Content)
+	image, _, _ := feedImage(*p)
+	imageAlt := ""
+	if p.ImageSet != nil {
+		imageAlt = p.ImageSet.Alt
+	}
+	p.SEO = &models.SEO{
+		Description:        description,
+		CanonicalURL:       postURL(*p),
+		OGType:             "article",
+		OGTitle:            p.Title,
+		OGDescription:      description,
+		OGImage:            image,
+		OGImageAlt:         imageAlt,
+		TwitterCard:        "summary_large_image",
+		TwitterTitle:       p.Title,
+		TwitterDescription: description,
+		TwitterImage:       image,
+		TwitterImageAlt:    imageAlt,
+	}
+	if image == "" {
+		p.SEO.TwitterCard = "summary"
+	}
+	applySearchTokens(p)
+}
+
+// refreshPostSEO คำนวณ SEO และ token ค้นหาใหม่หลังแก้ไขโพสต์
+func refreshPostSEO(id primitive.ObjectID) {
+	coll := database.GetCollection("posts")
+	var post models.Post
+	if err := coll.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&post); err != nil {
+		return
+	}
+	applySEO(&post)
+	_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"title":          post.Title,
+		"slug":           post.Slug,
+		"seo":            post.SEO,
+		"search_title":   post.SearchTitle,
+		"search_body":    post.SearchBody,
+		"search_version": post.SearchVersion,
+	}})
+}
+
+// backfillSEO เติม slug/SEO ให้โพสต์เก่าที่ยังไม่มี
+func backfillSEO() {
+	cursor, err := database.GetCollection("posts").Find(context.TODO(),
+		bson.M{"slug": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return
+	}
+	var ids []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	_ = cursor.All(context.TODO(), &ids)
+	for _, row := range ids {
+		refreshPostSEO(row.ID)
+	}
+	if len(ids) > 0 {
+		log.Printf("🔎 เติมข้อมูล SEO ให้โพสต์เก่า %d โพสต์", len(ids))
+	}
+}
+
+// --- sitemap.xml / robots.txt ---
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 sitemapindex"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// getSitemap ถ้าโพสต์เกิน sitemapPageSize จะคืน sitemap index ที่ชี้ไป /sitemaps/posts-N.xml
+func getSitemap(c *gin.Context) {
+	total, err := database.GetCollection("posts").CountDocuments(context.TODO(), publishedFilter())
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if total <= sitemapPageSize {
+		serveSitemapPage(c, 1)
+		return
+	}
+
+	pages := int((total + sitemapPageSize - 1) / sitemapPageSize)
+	index := sitemapIndex{Sitemaps: make([]sitemapURL, 0, pages)}
+	for i := 1; i <= pages; i++ {
+		index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: fmt.Sprintf("%s/sitemaps/posts-%d.xml", publicBaseURL(), i)})
+	}
+	serveXML(c, "application/xml; charset=utf-8", index, time.Time{})
+}
+
+func getSitemapPage(c *gin.Context) {
+	name := strings.TrimSuffix(strings.TrimPrefix(c.Param("file"), "posts-"), ".xml")
+	page, err := strconv.Atoi(name)
+	if err != nil || page < 1 {
+		respondError(c, 404, errCodeNotFound, "Sitemap not found")
+		return
+	}
+	serveSitemapPage(c, page)
+}
+
+func serveSitemapPage(c *gin.Context, page int) {
+	opts := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetSkip(int64((page - 1) * sitemapPageSize)).
+		SetLimit(sitemapPageSize).
+		SetProjection(bson.M{"_id": 1, "slug": 1, "created_at": 1, "published_at": 1, "updated_at": 1})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), publishedFilter(), opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	posts := make([]models.Post, 0)
+	_ = cursor.All(context.TODO(), &posts)
+	if page > 1 && len(posts) == 0 {
+		respondError(c, 404, errCodeNotFound, "Sitemap not found")
+		return
+	}
+
+	set := sitemapURLSet{URLs: make([]sitemapURL, 0, len(posts)+1)}
+	if page == 1 {
+		set.URLs = append(set.URLs, sitemapURL{Loc: siteURL() + "/"})
+	}
+	for _, p := range posts {
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     postURL(p),
+			LastMod: postLastModified(p).UTC().Format("2006-01-02"),
+		})
+	}
+	serveXML(c, "application/xml; charset=utf-8", set, feedLastModified(posts))
+}
+
+func getRobots(c *gin.Context) {
+	body := "User-agent: *\n" +
+		"Disallow: /api/\n" +
+		"Allow: /\n\n" +
+		"Sitemap: " + publicBaseURL() + "/sitemap.xml\n"
+	c.Header("Cache-Control", publicCacheHeader)
+	c.String(200, body)
+}
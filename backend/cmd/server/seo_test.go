@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"ห้องนอนสไตล์มินิมอล", "hongnon-stai-minimol"},
+		{"แต่งบ้านประหยัดพลังงาน", "taeng-ban-prahyadphlangngan"},
+		{"IKEA Sofa ๓ ที่นั่ง", "ikea-sofa-3-thi-nang"},
+		{"Modern Living Room!", "modern-living-room"},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.in); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
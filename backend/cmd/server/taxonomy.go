@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/generative-ai-go/genai"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxPostTags       = 10
+	tagMaxRunes       = 40
+	maxSuggestedTags  = 5
+	maxCategoryDepth  = 5
+	maxPostCategories = 20
+)
+
+// categoryNode หมวดหมู่พร้อมจำนวนโพสต์และหมวดย่อย
+type categoryNode struct {
+	models.Category
+	PostCount int64          `json:"postCount"`
+	Children  []categoryNode `json:"children"`
+}
+
+// tagCount จำนวนโพสต์ต่อ tag
+type tagCount struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+func ensureTaxonomyIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := database.GetCollection("categories").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index ของ categories ได้: ", err)
+	}
+	_, err = database.GetCollection("posts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "category_ids", Value: 1}}},
+	})
+	if err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index ของ tags/category_ids ได้: ", err)
+	}
+}
+
+// normalizeTag ตัวพิมพ์เล็ก ตัด # และช่องว่างซ้ำ เพื่อให้ "Smart Home" กับ "#smart  home" เป็น tag เดียวกัน
+func normalizeTag(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))), " ")
+	if utf8.RuneCountInString(s) > tagMaxRunes {
+		s = strings.TrimSpace(string([]rune(s)[:tagMaxRunes]))
+	}
+	return s
+}
+
+// normalizeTags normalize ตัดค่าซ้ำ และจำกัดไม่เกิน maxPostTags
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = normalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+		if len(out) == maxPostTags {
+			break
+		}
+	}
+	return out
+}
+
+func loadCategories() ([]models.Category, error) {
+	cursor, err := database.GetCollection("categories").Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	cats := make([]models.Category, 0)
+	err = cursor.All(context.TODO(), &cats)
+	return cats, err
+}
+
+// categoryDescendants id ของหมวดหมู่นี้และหมวดย่อยทั้งหมด
+func categoryDescendants(cats []models.Category, root primitive.ObjectID) []primitive.ObjectID {
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, cat := range cats {
+		if cat.ParentID != nil {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat.ID)
+		}
+	}
+	ids := []primitive.ObjectID{root}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
+
+// categoryDepth ความลึกของหมวดหมู่ (หมวดบนสุด = 1)
+func categoryDepth(cats []models.Category, id primitive.ObjectID) int {
+	parent := make(map[primitive.ObjectID]*primitive.ObjectID, len(cats))
+	for _, cat := range cats {
+		parent[cat.ID] = cat.ParentID
+	}
+	depth := 0
+	for cur := &id; cur != nil && depth <= len(cats); cur = parent[*cur] {
+		depth++
+	}
+	return depth
+}
+
+// categoryHeight จำนวนชั้นของหมวดหมู่นี้รวมหมวดย่อยทั้งหมด (ไม่มีหมวดย่อย = 1)
+func categoryHeight(cats []models.Category, root primitive.ObjectID) int {
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, cat := range cats {
+		if cat.ParentID != nil {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat.ID)
+		}
+	}
+	height := 0
+	for level := []primitive.ObjectID{root}; len(level) > 0 && height <= len(cats); height++ {
+		next := make([]primitive.ObjectID, 0)
+		for _, id := range level {
+			next = append(next, children[id]...)
+		}
+		level = next
+	}
+	return height
+}
+
+// resolveCategoryIDs แปลง id จาก request และตรวจว่ามีหมวดหมู่นั้นจริง
+func resolveCategoryIDs(hexes []string) ([]primitive.ObjectID, []fieldError) {
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	seen := make(map[primitive.ObjectID]bool, len(hexes))
+	for _, h := range hexes {
+		id, err := primitive.ObjectIDFromHex(h)
+		if err != nil {
+			return nil, []fieldError{{Field: "categoryIds", Rule: "mongodb", Message: "Invalid category id " + h}}
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+	n, err := database.GetCollection("categories").CountDocuments(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil || n != int64(len(ids)) {
+		return nil, []fieldError{{Field: "categoryIds", Rule: "exists", Message: "Some categories do not exist"}}
+	}
+	return ids, nil
+}
+
+// categoryForTopic หมวดหมู่ของ topic ที่ใช้สร้างโพสต์ สร้างใหม่เป็นหมวดบนสุดถ้ายังไม่มี
+func categoryForTopic(topic string) (primitive.ObjectID, bool) {
+	topic = strings.TrimSpace(topic)
+	slug := slugify(topic)
+	if slug == "" {
+		return primitive.NilObjectID, false
+	}
+	now := time.Now()
+	var cat models.Category
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := database.GetCollection("categories").FindOneAndUpdate(context.TODO(),
+		bson.M{"slug": slug},
+		bson.M{"$setOnInsert": bson.M{"name": topic, "slug": slug, "created_at": now, "updated_at": now}},
+		opts).Decode(&cat)
+	if err != nil {
+		log.Println("⚠️ สร้างหมวดหมู่จาก topic ไม่สำเร็จ: ", err)
+		return primitive.NilObjectID, false
+	}
+	return cat.ID, true
+}
+
+// suggestTags ให้ Gemini เสนอ tag สั้นๆ จากเนื้อหา (ไม่สำเร็จก็คืน nil โพสต์ยังสร้างต่อได้)
+func suggestTags(content, profile, user, jobID string) []string {
+	if geminiClient == nil {
+		return nil
+	}
+	model := geminiClient.GenerativeModel(getTextModel())
+	model.ResponseMIMEType = "application/json"
+
+	prompt := fmt.Sprintf(
+		"เสนอ tag ภาษาไทยหรืออังกฤษสั้นๆ %d คำที่อธิบายเนื้อหาต่อไปนี้ "+
+			"ตอบเป็น JSON array ของ string เท่านั้น เช่น [\"ห้องนอน\",\"minimal\"]\n\n%s",
+		maxSuggestedTags, content)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		log.Println("⚠️ ขอ tag จาก AI ไม่สำเร็จ: ", err)
+		return nil
+	}
+	if resp.UsageMetadata != nil {
+		recordTextUsage(textGeneration{
+			Model:           getTextModel(),
+			PromptTokens:    int64(resp.UsageMetadata.PromptTokenCount),
+			CandidateTokens: int64(resp.UsageMetadata.CandidatesTokenCount),
+		}, profile, user, jobID)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil
+	}
+
+	var tags []string
+	raw := strings.TrimSpace(fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]))
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+		log.Println("⚠️ tag จาก AI ไม่ใช่ JSON array: ", err)
+		return nil
+	}
+	if len(tags) > maxSuggestedTags {
+		tags = tags[:maxSuggestedTags]
+	}
+	return tags
+}
+
+// autoTagPost ใส่ topic เป็นทั้ง tag และหมวดหมู่ แล้วต่อด้วย tag ที่ AI เสนอ
+func autoTagPost(p *models.Post, suggested []string) {
+	if p.Topic == "" && len(suggested) == 0 {
+		return
+	}
+	p.Tags = normalizeTags(append(append([]string{p.Topic}, p.Tags...), suggested...))
+	if p.Topic != "" {
+		if id, ok := categoryForTopic(p.Topic); ok {
+			p.CategoryIDs = append(p.CategoryIDs, id)
+		}
+	}
+}
+
+// tagCounts จำนวนโพสต์ต่อ tag ของโพสต์ที่ตรงกับ filter เรียงจากมากไปน้อย
+func tagCounts(filter bson.M) ([]tagCount, error) {
+	cursor, err := database.GetCollection("posts").Aggregate(context.TODO(), bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]tagCount, 0)
+	err = cursor.All(context.TODO(), &rows)
+	return rows, err
+}
+
+// getTags จำนวนโพสต์ต่อ tag (ไม่นับโพสต์ในถังขยะ)
+func getTags(c *gin.Context) {
+	rows, err := tagCounts(bson.M{"deleted_at": bson.M{"$exists": false}})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, rows)
+}
+
+// getPublicTags จำนวนโพสต์ที่เผยแพร่แล้วต่อ tag
+func getPublicTags(c *gin.Context) {
+	rows, err := tagCounts(publishedFilter())
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	serveCachedJSON(c, rows, time.Time{})
+}
+
+// getCategories ต้นไม้หมวดหมู่ พร้อมจำนวนโพสต์ของแต่ละหมวด (รวมหมวดย่อย)
+func getCategories(c *gin.Context) {
+	cats, err := loadCategories()
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	cursor, err := database.GetCollection("posts").Aggregate(context.TODO(), bson.A{
+		bson.M{"$match": bson.M{"deleted_at": bson.M{"$exists": false}, "category_ids.0": bson.M{"$exists": true}}},
+		bson.M{"$unwind": "$category_ids"},
+		bson.M{"$group": bson.M{"_id": "$category_ids", "count": bson.M{"$sum": 1}}},
+	})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	_ = cursor.All(context.TODO(), &rows)
+	direct := make(map[primitive.ObjectID]int64, len(rows))
+	for _, r := range rows {
+		direct[r.ID] = r.Count
+	}
+
+	children := make(map[primitive.ObjectID][]models.Category)
+	roots := make([]models.Category, 0)
+	for _, cat := range cats {
+		if cat.ParentID == nil {
+			roots = append(roots, cat)
+		} else {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		}
+	}
+
+	var build func(list []models.Category, depth int) []categoryNode
+	build = func(list []models.Category, depth int) []categoryNode {
+		out := make([]categoryNode, 0, len(list))
+		for _, cat := range list {
+			node := categoryNode{Category: cat, PostCount: direct[cat.ID], Children: []categoryNode{}}
+			if depth < maxCategoryDepth {
+				node.Children = build(children[cat.ID], depth+1)
+			}
+			// โพสต์ที่อยู่หลายหมวดย่อยของหมวดเดียวกันจะถูกนับซ้ำ ถือเป็นค่าประมาณ
+			for _, child := range node.Children {
+				node.PostCount += child.PostCount
+			}
+			out = append(out, node)
+		}
+		return out
+	}
+	c.JSON(200, build(roots, 1))
+}
+
+type categoryRequest struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=80"`
+	Slug        *string `json:"slug" binding:"omitempty,min=1,max=80"`
+	ParentID    *string `json:"parentId" binding:"omitempty,max=24"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+}
+
+// parentFor ตรวจ parentId ("" = ย้ายไปเป็นหมวดบนสุด) กันการวนลูปและความลึกเกินกำหนด
+func (r categoryRequest) parentFor(cats []models.Category, self primitive.ObjectID) (*primitive.ObjectID, []fieldError) {
+	if *r.ParentID == "" {
+		return nil, nil
+	}
+	parentID, err := primitive.ObjectIDFromHex(*r.ParentID)
+	if err != nil {
+		return nil, []fieldError{{Field: "parentId", Rule: "mongodb", Message: "Invalid parentId"}}
+	}
+	found := false
+	for _, cat := range cats {
+		if cat.ID == parentID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, []fieldError{{Field: "parentId", Rule: "exists", Message: "Parent category not found"}}
+	}
+	if !self.IsZero() {
+		for _, id := range categoryDescendants(cats, self) {
+			if id == parentID {
+				return nil, []fieldError{{Field: "parentId", Rule: "cycle", Message: "Category cannot be moved under itself"}}
+			}
+		}
+	}
+	// หมวดที่ย้ายพาหมวดย่อยไปด้วย จึงต้องนับความสูงของทั้ง subtree
+	height := 1
+	if !self.IsZero() {
+		height = categoryHeight(cats, self)
+	}
+	if categoryDepth(cats, parentID)+height > maxCategoryDepth {
+		return nil, []fieldError{{Field: "parentId", Rule: "depth", Message: fmt.Sprintf("Categories can be nested at most %d levels", maxCategoryDepth)}}
+	}
+	return &parentID, nil
+}
+
+func createCategory(c *gin.Context) {
+	var req categoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{Field: "name", Rule: "required", Message: "name is required"})
+		return
+	}
+
+	now := time.Now()
+	cat := models.Category{
+		ID:        primitive.NewObjectID(),
+		Name:      strings.TrimSpace(*req.Name),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Description != nil {
+		cat.Description = strings.TrimSpace(*req.Description)
+	}
+	cat.Slug = slugify(cat.Name)
+	if req.Slug != nil {
+		cat.Slug = *req.Slug
+	}
+	if cat.Slug == "" {
+		cat.Slug = cat.ID.Hex()
+	}
+	if !slugPattern.MatchString(cat.Slug) {
+		respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{Field: "slug", Rule: "slug", Message: "slug must contain only a-z, 0-9 and single hyphens"})
+		return
+	}
+	if req.ParentID != nil {
+		cats, err := loadCategories()
+		if err != nil {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		parent, errs := req.parentFor(cats, primitive.NilObjectID)
+		if len(errs) > 0 {
+			respondError(c, 400, errCodeValidationFailed, "Validation failed", errs...)
+			return
+		}
+		cat.ParentID = parent
+	}
+
+	if _, err := database.GetCollection("categories").InsertOne(context.TODO(), cat); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			respondError(c, 409, errCodeConflict, "Category slug already exists")
+			return
+		}
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(201, cat)
+}
+
+func updateCategory(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var req categoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+	if req.Name != nil {
+		set["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		set["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Slug != nil {
+		if !slugPattern.MatchString(*req.Slug) {
+			respondError(c, 400, errCodeValidationFailed, "Validation failed", fieldError{Field: "slug", Rule: "slug", Message: "slug must contain only a-z, 0-9 and single hyphens"})
+			return
+		}
+		set["slug"] = *req.Slug
+	}
+	if req.ParentID != nil {
+		cats, err := loadCategories()
+		if err != nil {
+			respondError(c, 500, errCodeInternal, err.Error())
+			return
+		}
+		parent, errs := req.parentFor(cats, id)
+		if len(errs) > 0 {
+			respondError(c, 400, errCodeValidationFailed, "Validation failed", errs...)
+			return
+		}
+		if parent == nil {
+			unset["parent_id"] = ""
+		} else {
+			set["parent_id"] = *parent
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	var cat models.Category
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := database.GetCollection("categories").FindOneAndUpdate(context.TODO(), bson.M{"_id": id}, update, opts).Decode(&cat)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			respondError(c, 409, errCodeConflict, "Category slug already exists")
+			return
+		}
+		respondError(c, 404, errCodeNotFound, "Category not found")
+		return
+	}
+	c.JSON(200, cat)
+}
+
+// deleteCategory ลบหมวดหมู่ หมวดย่อยจะย้ายขึ้นไปอยู่ใต้ parent เดิม และเอาหมวดนี้ออกจากโพสต์
+func deleteCategory(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	coll := database.GetCollection("categories")
+	var cat models.Category
+	if err := coll.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&cat); err != nil {
+		respondError(c, 404, errCodeNotFound, "Category not found")
+		return
+	}
+
+	reparent := bson.M{"$unset": bson.M{"parent_id": ""}}
+	if cat.ParentID != nil {
+		reparent = bson.M{"$set": bson.M{"parent_id": *cat.ParentID}}
+	}
+	if _, err := coll.UpdateMany(context.TODO(), bson.M{"parent_id": id}, reparent); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	res, err := database.GetCollection("posts").UpdateMany(context.TODO(),
+		bson.M{"category_ids": id},
+		bson.M{"$pull": bson.M{"category_ids": id}})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if _, err := coll.DeleteOne(context.TODO(), bson.M{"_id": id}); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"status": "deleted", "postsUpdated": res.ModifiedCount})
+}
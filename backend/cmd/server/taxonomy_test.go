@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// categoryChain หมวดหมู่ซ้อนกัน n ชั้น ตัวแรกเป็นหมวดบนสุด
+func categoryChain(n int) []models.Category {
+	cats := make([]models.Category, 0, n)
+	var parent *primitive.ObjectID
+	for i := 0; i < n; i++ {
+		id := primitive.NewObjectID()
+		cats = append(cats, models.Category{ID: id, ParentID: parent})
+		parent = &cats[i].ID
+	}
+	return cats
+}
+
+func TestParentForCountsMovedSubtree(t *testing.T) {
+	deep := categoryChain(3)
+	moved := categoryChain(3)
+	cats := append(append([]models.Category{}, deep...), moved...)
+	parent := deep[2].ID.Hex()
+	req := categoryRequest{ParentID: &parent}
+
+	// ชั้นที่ 3 + subtree สูง 3 = 6 เกิน maxCategoryDepth
+	if _, errs := req.parentFor(cats, moved[0].ID); len(errs) == 0 || errs[0].Rule != "depth" {
+		t.Fatalf("moving a 3-level subtree under depth 3 = %v, want depth error", errs)
+	}
+	// หมวดล่างสุดของ subtree (สูง 1) ย้ายได้ เพราะลึกรวม 4
+	if _, errs := req.parentFor(cats, moved[2].ID); len(errs) > 0 {
+		t.Fatalf("moving a leaf under depth 3 = %v, want ok", errs)
+	}
+	// สร้างหมวดใหม่ใต้ชั้นที่ 3 ได้
+	if _, errs := req.parentFor(cats, primitive.NilObjectID); len(errs) > 0 {
+		t.Fatalf("creating under depth 3 = %v, want ok", errs)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+)
+
+// thai_icu.txt คือรายการคำไทยของ ICU (ดู dict/README.md) ส่วน thai_words.txt เป็นคำเฉพาะด้านตกแต่งบ้านที่ ICU ไม่มี
+//
+//go:embed dict/thai_icu.txt
+var thaiICUWordList string
+
+//go:embed thai_words.txt
+var thaiWordList string
+
+var (
+	thaiDict       map[string]bool
+	thaiDictMaxLen int
+)
+
+func init() {
+	thaiDict = make(map[string]bool)
+	for _, list := range []string{thaiICUWordList, thaiWordList} {
+		for _, line := range strings.Split(list, "\n") {
+			w := strings.TrimSpace(line)
+			if w == "" || strings.HasPrefix(w, "#") {
+				continue
+			}
+			thaiDict[w] = true
+			if n := len([]rune(w)); n > thaiDictMaxLen {
+				thaiDictMaxLen = n
+			}
+		}
+	}
+}
+
+// isThaiLetter อักษรไทยที่ไม่ใช่ตัวเลข (ตัวเลขไทยถูกจัดเป็น token ตัวเลขปกติ)
+func isThaiLetter(r rune) bool {
+	return r >= 0x0E01 && r <= 0x0E5B && !unicode.IsDigit(r)
+}
+
+// สระ/วรรณยุกต์ที่ต้องติดกับพยัญชนะก่อนหน้าเสมอ
+func isThaiTrailing(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || r == 'ะ' || r == 'า' || r == 'ำ' || r == 'ๅ' || r == 'ฯ'
+}
+
+// thaiClusterEnd คืนตำแหน่งสิ้นสุดของกลุ่มอักษร (Thai Character Cluster แบบย่อ) ที่เริ่มที่ i
+// กลุ่มอักษรคือหน่วยที่เล็กที่สุดที่ตัดแยกไม่ได้ เช่น เก, ก้า, ที่
+func thaiClusterEnd(runes []rune, i int) int {
+	j := i
+	if thaiLeadingVowels[runes[j]] {
+		j++
+	}
+	if j < len(runes) {
+		j++
+	}
+	for j < len(runes) && isThaiTrailing(runes[j]) {
+		j++
+	}
+	// พยัญชนะที่ตามด้วยการันต์ถือเป็นส่วนของกลุ่มก่อนหน้า
+	if j+1 < len(runes) && runes[j+1] == '์' {
+		j += 2
+	}
+	return j
+}
+
+// thaiClusterBounds ตำแหน่งขอบกลุ่มอักษรทั้งหมด (รวม 0 และ len(runes)) คำที่ตัดได้ต้องเริ่มและจบที่ขอบเหล่านี้
+func thaiClusterBounds(runes []rune) []int {
+	bounds := []int{0}
+	for i := 0; i < len(runes); {
+		i = thaiClusterEnd(runes, i)
+		bounds = append(bounds, i)
+	}
+	return bounds
+}
+
+// segmentThai ตัดคำข้อความไทยแบบ maximal matching: เลือกทางที่มีกลุ่มอักษรนอกพจนานุกรมน้อยที่สุด
+// แล้วจึงเลือกทางที่ได้จำนวนคำน้อยที่สุด กลุ่มอักษรที่ไม่รู้จักซึ่งอยู่ติดกันจะรวมเป็น token เดียว
+func segmentThai(runes []rune) []string {
+	bounds := thaiClusterBounds(runes)
+	type step struct {
+		unknown, words int
+		prev           int
+		known          bool
+	}
+	best := make([]step, len(bounds))
+	for k := 1; k < len(bounds); k++ {
+		best[k] = step{unknown: -1}
+	}
+	better := func(a, b step) bool {
+		return b.unknown < 0 || a.unknown < b.unknown || (a.unknown == b.unknown && a.words < b.words)
+	}
+
+	for k := 0; k < len(bounds)-1; k++ {
+		cur := best[k]
+		// กลุ่มอักษรเดียวที่ไม่อยู่ในพจนานุกรม
+		if cand := (step{unknown: cur.unknown + 1, words: cur.words + 1, prev: k}); better(cand, best[k+1]) {
+			best[k+1] = cand
+		}
+		for m := k + 1; m < len(bounds) && bounds[m]-bounds[k] <= thaiDictMaxLen; m++ {
+			if !thaiDict[string(runes[bounds[k]:bounds[m]])] {
+				continue
+			}
+			if cand := (step{unknown: cur.unknown, words: cur.words + 1, prev: k, known: true}); better(cand, best[m]) {
+				best[m] = cand
+			}
+		}
+	}
+
+	path := make([]int, 0)
+	for k := len(bounds) - 1; k > 0; k = best[k].prev {
+		path = append(path, k)
+	}
+	words := make([]string, 0, len(path))
+	unknownFrom := -1
+	for i := len(path) - 1; i >= 0; i-- {
+		k := path[i]
+		from := bounds[best[k].prev]
+		if !best[k].known {
+			if unknownFrom < 0 {
+				unknownFrom = from
+			}
+			continue
+		}
+		if unknownFrom >= 0 {
+			words = append(words, string(runes[unknownFrom:from]))
+			unknownFrom = -1
+		}
+		words = append(words, string(runes[from:bounds[k]]))
+	}
+	if unknownFrom >= 0 {
+		words = append(words, string(runes[unknownFrom:]))
+	}
+	return words
+}
+
+// thaiWordBreaks เว้นวรรคระหว่างคำไทยที่เขียนติดกัน ข้อความส่วนอื่นคงไว้ตามเดิม
+func thaiWordBreaks(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if !isThaiLetter(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isThaiLetter(runes[j]) {
+			j++
+		}
+		b.WriteString(strings.Join(segmentThai(runes[i:j]), " "))
+		i = j
+	}
+	return b.String()
+}
+
+// tokenizeText แยกข้อความเป็น token สำหรับค้นหา: ภาษาไทยผ่าน segmentThai, ภาษาอื่นตัดด้วยช่องว่าง/เครื่องหมาย
+func tokenizeText(s string) []string {
+	tokens := make([]string, 0)
+	runes := []rune(strings.ToLower(s))
+	for i, r := range runes {
+		if d, ok := thaiDigits[r]; ok {
+			runes[i] = d
+		}
+	}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case isThaiLetter(r):
+			j := i
+			for j < len(runes) && isThaiLetter(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, segmentThai(runes[i:j])...)
+			i = j
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && !isThaiLetter(runes[j]) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// indexTokens token สำหรับเก็บลง index: เพิ่มคำในพจนานุกรมทุกคำที่อยู่ในคำประสม
+// เพื่อให้ค้น "ห้อง" แล้วเจอ "ห้องนอน" และค้น "พลังงาน" แล้วเจอ "ประหยัดพลังงาน"
+// คำย่อยต้องเป็นส่วนหนึ่งของการตัดคำประสมทั้งคำด้วยพจนานุกรมได้ จึงไม่เก็บ "แต่" จาก "แต่ง"
+func indexTokens(s string) []string {
+	tokens := tokenizeText(s)
+	out := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, t)
+		runes := []rune(t)
+		if !isThaiLetter(runes[0]) {
+			continue
+		}
+		bounds := thaiClusterBounds(runes)
+		last := len(bounds) - 1
+		word := func(a, b int) bool {
+			return bounds[b]-bounds[a] <= thaiDictMaxLen && thaiDict[string(runes[bounds[a]:bounds[b]])]
+		}
+		// fromStart[k]: runes[:bounds[k]] ตัดเป็นคำได้ครบ, toEnd[k]: runes[bounds[k]:] ตัดเป็นคำได้ครบ
+		fromStart := make([]bool, len(bounds))
+		toEnd := make([]bool, len(bounds))
+		fromStart[0], toEnd[last] = true, true
+		for b := 1; b <= last; b++ {
+			for a := 0; a < b && !fromStart[b]; a++ {
+				fromStart[b] = fromStart[a] && word(a, b)
+			}
+		}
+		for a := last - 1; a >= 0; a-- {
+			for b := last; b > a && !toEnd[a]; b-- {
+				toEnd[a] = toEnd[b] && word(a, b)
+			}
+		}
+
+		seen := map[string]bool{t: true}
+		for a := 0; a < last; a++ {
+			if !fromStart[a] {
+				continue
+			}
+			for b := a + 1; b <= last; b++ {
+				if !toEnd[b] || !word(a, b) {
+					continue
+				}
+				if sub := string(runes[bounds[a]:bounds[b]]); !seen[sub] {
+					seen[sub] = true
+					out = append(out, sub)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTokenizeText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"แต่งบ้าน", []string{"แต่ง", "บ้าน"}},
+		{"ห้องนอนสไตล์มินิมอล", []string{"ห้องนอน", "สไตล์", "มินิมอล"}},
+		{"ตกแต่งห้องนั่งเล่น", []string{"ตกแต่ง", "ห้องนั่งเล่น"}},
+		{"Sofa สีเทา, ๒ ที่", []string{"sofa", "สีเทา", "2", "ที่"}},
+		{"IKEA-style 2024", []string{"ikea", "style", "2024"}},
+		{"", []string{}},
+	}
+	for _, tt := range tests {
+		if got := tokenizeText(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSegmentThaiKeepsUnknownClustersTogether(t *testing.T) {
+	// "ฟฟฟ" ไม่ใช่คำ ต้องออกมาเป็น token เดียว ไม่แตกเป็นตัวอักษร
+	got := segmentThai([]rune("บ้านฟฟฟสวย"))
+	want := []string{"บ้าน", "ฟฟฟ", "สวย"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("segmentThai = %q, want %q", got, want)
+	}
+}
+
+func TestIndexTokens(t *testing.T) {
+	tests := []struct {
+		in       string
+		contains []string
+		excludes []string
+	}{
+		// คำย่อยท้ายคำประสมต้องค้นเจอ ไม่ใช่แค่ส่วนต้น
+		{"ประหยัดพลังงาน", []string{"ประหยัดพลังงาน", "ประหยัด", "พลังงาน", "พลัง"}, nil},
+		{"ห้องนอน", []string{"ห้องนอน", "ห้อง", "นอน"}, nil},
+		// คำย่อยต้องจบที่ขอบกลุ่มอักษร "แต่" ไม่ใช่คำใน "แต่ง"
+		{"แต่งบ้าน", []string{"แต่ง", "บ้าน"}, []string{"แต่", "บ้า"}},
+		{"สีเทา", []string{"สีเทา", "สี", "เทา"}, nil},
+		{"Sofa", []string{"sofa"}, nil},
+	}
+	for _, tt := range tests {
+		got := indexTokens(tt.in)
+		for _, w := range tt.contains {
+			if !slices.Contains(got, w) {
+				t.Errorf("indexTokens(%q) = %q, missing %q", tt.in, got, w)
+			}
+		}
+		for _, w := range tt.excludes {
+			if slices.Contains(got, w) {
+				t.Errorf("indexTokens(%q) = %q, should not contain %q", tt.in, got, w)
+			}
+		}
+	}
+}
+
+func TestHighlightText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		terms  []string
+		window int
+		want   string
+	}{
+		{"thai term", "ไอเดียประหยัดพลังงานในบ้าน", []string{"พลังงาน"}, 0, "ไอเดียประหยัด<mark>พลังงาน</mark>ในบ้าน"},
+		{"case insensitive", "Modern Sofa", []string{"sofa"}, 0, "Modern <mark>Sofa</mark>"},
+		{"adjacent terms merge", "แต่งบ้าน", []string{"แต่ง", "บ้าน"}, 0, "<mark>แต่งบ้าน</mark>"},
+		{"thai digits", "โซฟา ๓ ที่นั่ง", []string{"3"}, 0, "โซฟา <mark>๓</mark> ที่นั่ง"},
+		{"escapes html", "<b>sofa</b>", []string{"sofa"}, 0, "&lt;b&gt;<mark>sofa</mark>&lt;/b&gt;"},
+		{"window", "abcdefghij sofa klmnopqrst", []string{"sofa"}, 8, "…hij <mark>sofa</mark>…"},
+		{"no match", "โซฟา", []string{"โต๊ะ"}, 0, ""},
+	}
+	for _, tt := range tests {
+		if got := highlightText(tt.text, tt.terms, tt.window); got != tt.want {
+			t.Errorf("%s: highlightText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxImportBytes  = 200 << 20
+	maxImportLine   = 32 << 20
+	utf8BOM         = "\xEF\xBB\xBF"
+	bundlePostsFile = "posts.jsonl"
+	bundleCatsFile  = "categories.json"
+	bundleMediaDir  = "media/"
+	csvListSep      = "|"
+
+	// ขนาดรวมหลังแตก zip และขนาดรูปต่อไฟล์ (ประมาณ data URL 15 MB หลัง base64) กัน zip bomb
+	maxImportUnpackedBytes = 1 << 30
+	maxBundleImageBytes    = 11 << 20
+)
+
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// transferPost รูปแบบโพสต์ใน export/import แยกจาก models.Post เพื่อให้ไฟล์ย้ายข้าม environment ได้คงที่
+type transferPost struct {
+	ID          string     `json:"id" binding:"omitempty,mongodb"`
+	User        string     `json:"user" binding:"required,max=100"`
+	Title       string     `json:"title" binding:"max=200"`
+	Slug        string     `json:"slug" binding:"omitempty,max=80"`
+	Status      string     `json:"status" binding:"omitempty,oneof=published review rejected archived"`
+	Topic       string     `json:"topic" binding:"max=200"`
+	Tags        []string   `json:"tags" binding:"max=10,dive,max=40"`
+	CategoryIDs []string   `json:"categoryIds" binding:"max=20,dive,mongodb"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	PublishedAt *time.Time `json:"publishedAt"`
+	Likes       int64      `json:"likes" binding:"min=0"`
+	Comments    int64      `json:"comments" binding:"min=0"`
+	Image       string     `json:"image" binding:"max=15728640"`
+	Content     string     `json:"content" binding:"required,max=20000"`
+}
+
+var transferCSVHeader = []string{"id", "user", "title", "slug", "status", "topic", "tags", "categoryIds", "createdAt", "publishedAt", "likes", "comments", "image", "content"}
+
+// importRowResult ผลการนำเข้าของแต่ละแถว (row นับจาก 1 ตามบรรทัดข้อมูลในไฟล์)
+type importRowResult struct {
+	Row     int          `json:"row"`
+	ID      string       `json:"id,omitempty"`
+	Status  string       `json:"status"`
+	Reason  string       `json:"reason,omitempty"`
+	Details []fieldError `json:"details,omitempty"`
+}
+
+type importRow struct {
+	Row  int
+	Post transferPost
+	Err  string
+	// Media รูปใน bundle zip อ่านเป็น data URL ตอนบันทึกทีละแถว ไม่โหลดทุกรูปไว้พร้อมกัน
+	Media *zip.File
+}
+
+// loadMedia แปลงรูปจาก bundle เป็น data URL ใน Post.Image
+func (row *importRow) loadMedia() error {
+	if row.Media == nil {
+		return nil
+	}
+	name := row.Post.Image
+	r, err := row.Media.Open()
+	if err != nil {
+		return fmt.Errorf("cannot read %s", name)
+	}
+	defer r.Close()
+	raw, err := io.ReadAll(io.LimitReader(r, maxBundleImageBytes+1))
+	if err != nil {
+		return fmt.Errorf("cannot read %s", name)
+	}
+	if len(raw) > maxBundleImageBytes {
+		return fmt.Errorf("media file %s is larger than %d MB", name, maxBundleImageBytes>>20)
+	}
+	mime := "application/octet-stream"
+	for m, ext := range imageExtensions {
+		if strings.HasSuffix(name, ext) {
+			mime = m
+		}
+	}
+	row.Post.Image = "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(raw)
+	return nil
+}
+
+func toTransferPost(p models.Post) transferPost {
+	t := transferPost{
+		ID:          p.ID.Hex(),
+		User:        p.User,
+		Title:       p.Title,
+		Slug:        p.Slug,
+		Status:      p.Status,
+		Topic:       p.Topic,
+		Tags:        p.Tags,
+		CategoryIDs: make([]string, 0, len(p.CategoryIDs)),
+		PublishedAt: p.PublishedAt,
+		Likes:       p.Likes,
+		Comments:    p.Comments,
+		Image:       p.Image,
+		Content:     p.Content,
+	}
+	if !p.CreatedAt.IsZero() {
+		created := p.CreatedAt
+		t.CreatedAt = &created
+	}
+	for _, id := range p.CategoryIDs {
+		t.CategoryIDs = append(t.CategoryIDs, id.Hex())
+	}
+	return t
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func (t transferPost) csvRecord() []string {
+	return []string{
+		t.ID, t.User, t.Title, t.Slug, t.Status, t.Topic,
+		strings.Join(t.Tags, csvListSep), strings.Join(t.CategoryIDs, csvListSep),
+		formatOptionalTime(t.CreatedAt), formatOptionalTime(t.PublishedAt),
+		strconv.FormatInt(t.Likes, 10), strconv.FormatInt(t.Comments, 10),
+		t.Image, t.Content,
+	}
+}
+
+// exportPosts ส่งออกโพสต์แบบ stream ?format=jsonl|csv|zip ใช้ filter เดียวกับรายการโพสต์
+func exportPosts(c *gin.Context) {
+	format := c.DefaultQuery("format", "jsonl")
+	if format != "jsonl" && format != "csv" && format != "zip" {
+		respondError(c, 400, errCodeValidationFailed, "format must be jsonl, csv or zip")
+		return
+	}
+	filter, ok := postListFilter(c)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetProjection(bson.M{"variants": 0, "search_title": 0, "search_body": 0})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), filter, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	name := "posts-" + time.Now().In(bangkokTZ).Format("20060102-1504")
+	switch format {
+	case "jsonl":
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.jsonl"`)
+		enc := json.NewEncoder(c.Writer)
+		for cursor.Next(context.TODO()) {
+			var p models.Post
+			if err := cursor.Decode(&p); err != nil {
+				continue
+			}
+			_ = enc.Encode(toTransferPost(p))
+			c.Writer.Flush()
+		}
+
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		// BOM ทำให้ Excel เปิดภาษาไทยเป็น UTF-8 ได้ถูกต้อง
+		_, _ = io.WriteString(c.Writer, utf8BOM)
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write(transferCSVHeader)
+		for cursor.Next(context.TODO()) {
+			var p models.Post
+			if err := cursor.Decode(&p); err != nil {
+				continue
+			}
+			t := toTransferPost(p)
+			// รูปแบบ data URL ยาวเกินกว่าที่ Excel รับได้ ใช้ลิงก์ media แทน
+			t.Image, _, _ = feedImage(p)
+			_ = w.Write(t.csvRecord())
+			w.Flush()
+			c.Writer.Flush()
+		}
+
+	case "zip":
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		zw := zip.NewWriter(c.Writer)
+		// เขียนรูปลง zip ทันทีทีละโพสต์ ส่วน posts.jsonl พักไว้ในไฟล์ชั่วคราวแล้วต่อท้าย zip
+		// (zip เขียนได้ทีละไฟล์) จึงไม่ต้องเก็บรูปทั้งหมดไว้ในหน่วยความจำ
+		tmp, err := os.CreateTemp("", "export-*.jsonl")
+		if err != nil {
+			return
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		enc := json.NewEncoder(tmp)
+		for cursor.Next(context.TODO()) {
+			var p models.Post
+			if err := cursor.Decode(&p); err != nil {
+				continue
+			}
+			t := toTransferPost(p)
+			mime, data, ok := parseDataURL(p.Image)
+			if !ok && p.ImageSet != nil {
+				mime, data, ok = loadMediaOriginal(p.ImageSet.MediaID)
+			}
+			if ok {
+				ext := imageExtensions[mime]
+				if ext == "" {
+					ext = ".bin"
+				}
+				t.Image = bundleMediaDir + p.ID.Hex() + ext
+				if f, err := zw.Create(t.Image); err == nil {
+					_, _ = f.Write(data)
+				}
+				c.Writer.Flush()
+			}
+			_ = enc.Encode(t)
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err == nil {
+			if f, err := zw.Create(bundlePostsFile); err == nil {
+				_, _ = io.Copy(f, tmp)
+			}
+		}
+		if cats, err := loadCategories(); err == nil {
+			if f, err := zw.Create(bundleCatsFile); err == nil {
+				_ = json.NewEncoder(f).Encode(cats)
+			}
+		}
+		_ = zw.Close()
+	}
+}
+
+// readImportPayload อ่านไฟล์จาก multipart field "file" หรือ body ตรงๆ และเดารูปแบบจากนามสกุล/Content-Type
+func readImportPayload(c *gin.Context) ([]byte, string, error) {
+	format := c.Query("format")
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("file is required")
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(path.Ext(fh.Filename)), ".")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		r = f
+	} else if format == "" {
+		switch c.ContentType() {
+		case "application/x-ndjson", "application/jsonl":
+			format = "jsonl"
+		case "text/csv":
+			format = "csv"
+		case "application/zip":
+			format = "zip"
+		}
+	}
+	if format != "jsonl" && format != "csv" && format != "zip" {
+		return nil, "", fmt.Errorf("format must be jsonl, csv or zip")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxImportBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImportBytes {
+		return nil, "", fmt.Errorf("file is larger than %d MB", maxImportBytes>>20)
+	}
+	return data, format, nil
+}
+
+func parseJSONLRows(r io.Reader) ([]importRow, error) {
+	rows := make([]importRow, 0)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+	n := 0
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		n++
+		row := importRow{Row: n}
+		if err := json.Unmarshal(line, &row.Post); err != nil {
+			row.Err = "invalid JSON: " + err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return rows, sc.Err()
+}
+
+func parseOptionalTime(s string) (*time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func splitCSVList(s string) []string {
+	out := make([]string, 0)
+	for _, v := range strings.Split(s, csvListSep) {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func parseCSVRows(data []byte) ([]importRow, error) {
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte(utf8BOM))))
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing CSV header: %w", err)
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(h)] = i
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	rows := make([]importRow, 0)
+	for n := 1; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		row := importRow{Row: n}
+		if err != nil {
+			row.Err = "invalid CSV: " + err.Error()
+			rows = append(rows, row)
+			continue
+		}
+		p := transferPost{
+			ID:          strings.TrimSpace(get(rec, "id")),
+			User:        get(rec, "user"),
+			Title:       get(rec, "title"),
+			Slug:        strings.TrimSpace(get(rec, "slug")),
+			Status:      strings.TrimSpace(get(rec, "status")),
+			Topic:       get(rec, "topic"),
+			Tags:        splitCSVList(get(rec, "tags")),
+			CategoryIDs: splitCSVList(get(rec, "categoryIds")),
+			Image:       strings.TrimSpace(get(rec, "image")),
+			Content:     get(rec, "content"),
+		}
+		var perr error
+		if p.CreatedAt, perr = parseOptionalTime(get(rec, "createdAt")); perr != nil {
+			row.Err = "createdAt must be RFC 3339"
+		} else if p.PublishedAt, perr = parseOptionalTime(get(rec, "publishedAt")); perr != nil {
+			row.Err = "publishedAt must be RFC 3339"
+		}
+		for _, f := range []struct {
+			name string
+			dst  *int64
+		}{{"likes", &p.Likes}, {"comments", &p.Comments}} {
+			if v := strings.TrimSpace(get(rec, f.name)); v != "" {
+				if *f.dst, perr = strconv.ParseInt(v, 10, 64); perr != nil {
+					row.Err = f.name + " must be a number"
+				}
+			}
+		}
+		row.Post = p
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseZipRows อ่าน posts.jsonl ใน bundle และผูกรูปใน media/ ไว้กับแถว (อ่านรูปจริงตอนบันทึก)
+func parseZipRows(data []byte, dryRun bool) ([]importRow, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	var unpacked uint64
+	for _, f := range zr.File {
+		files[f.Name] = f
+		// zip reader ตรวจว่าขนาดที่อ่านได้จริงตรงกับ header จึงใช้ขนาดใน header รวมได้
+		unpacked += f.UncompressedSize64
+	}
+	if unpacked > maxImportUnpackedBytes {
+		return nil, fmt.Errorf("zip unpacks to more than %d MB", maxImportUnpackedBytes>>20)
+	}
+	postsFile, ok := files[bundlePostsFile]
+	if !ok {
+		return nil, fmt.Errorf("zip has no %s", bundlePostsFile)
+	}
+	if postsFile.UncompressedSize64 > maxImportBytes {
+		return nil, fmt.Errorf("%s is larger than %d MB", bundlePostsFile, maxImportBytes>>20)
+	}
+	pr, err := postsFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer pr.Close()
+	rows, err := parseJSONLRows(io.LimitReader(pr, maxImportBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if f, ok := files[bundleCatsFile]; ok && !dryRun {
+		importBundleCategories(f)
+	}
+
+	for i := range rows {
+		img := rows[i].Post.Image
+		if rows[i].Err != "" || !strings.HasPrefix(img, bundleMediaDir) {
+			continue
+		}
+		f, ok := files[img]
+		if !ok {
+			rows[i].Err = "media file " + img + " is missing from the bundle"
+			continue
+		}
+		if f.UncompressedSize64 > maxBundleImageBytes {
+			rows[i].Err = fmt.Sprintf("media file %s is larger than %d MB", img, maxBundleImageBytes>>20)
+			continue
+		}
+		rows[i].Media = f
+	}
+	return rows, nil
+}
+
+// importBundleCategories เพิ่มหมวดหมู่จาก bundle ที่ยังไม่มี (ไม่เขียนทับหมวดหมู่ที่มีอยู่แล้ว)
+func importBundleCategories(f *zip.File) {
+	r, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	var cats []models.Category
+	if err := json.NewDecoder(r).Decode(&cats); err != nil {
+		return
+	}
+	coll := database.GetCollection("categories")
+	for _, cat := range cats {
+		_, _ = coll.UpdateOne(context.TODO(), bson.M{"_id": cat.ID}, bson.M{"$setOnInsert": cat}, options.Update().SetUpsert(true))
+	}
+}
+
+// importPosts นำเข้าโพสต์จาก jsonl/csv/zip ตรวจความถูกต้องทีละแถว ข้าม id หรือเนื้อหาที่ซ้ำ
+// ?dryRun=true จะตรวจอย่างเดียวไม่บันทึก
+func importPosts(c *gin.Context) {
+	data, format, err := readImportPayload(c)
+	if err != nil {
+		respondError(c, 400, errCodeInvalidBody, err.Error())
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	var rows []importRow
+	switch format {
+	case "jsonl":
+		rows, err = parseJSONLRows(bytes.NewReader(data))
+	case "csv":
+		rows, err = parseCSVRows(data)
+	case "zip":
+		rows, err = parseZipRows(data, dryRun)
+	}
+	if err != nil {
+		respondError(c, 400, errCodeInvalidBody, err.Error())
+		return
+	}
+
+	coll := database.GetCollection("posts")
+	actor := requestActor(c)
+	seenIDs := make(map[primitive.ObjectID]int)
+	seenFingerprints := make(map[string]int)
+	results := make([]importRowResult, 0, len(rows))
+	counts := map[string]int{"imported": 0, "skipped": 0, "error": 0}
+
+	for i := range rows {
+		row := &rows[i]
+		res := importRowResult{Row: row.Row, ID: row.Post.ID, Status: "error"}
+		func() {
+			if row.Err != "" {
+				res.Reason = row.Err
+				return
+			}
+			if err := binding.Validator.ValidateStruct(row.Post); err != nil {
+				res.Reason = "Validation failed"
+				res.Details, _ = validationDetails(err)
+				if res.Details == nil {
+					res.Reason = err.Error()
+				}
+				return
+			}
+
+			id := primitive.NewObjectID()
+			if row.Post.ID != "" {
+				id, _ = primitive.ObjectIDFromHex(row.Post.ID)
+				if first, ok := seenIDs[id]; ok {
+					res.Status, res.Reason = "skipped", fmt.Sprintf("duplicate id of row %d", first)
+					return
+				}
+				seenIDs[id] = row.Row
+				if n, _ := coll.CountDocuments(context.TODO(), bson.M{"_id": id}); n > 0 {
+					res.Status, res.Reason = "skipped", "post with this id already exists"
+					return
+				}
+			}
+			fingerprint := contentFingerprint(row.Post.Content)
+			if fingerprint != "" {
+				if first, ok := seenFingerprints[fingerprint]; ok {
+					res.Status, res.Reason = "skipped", fmt.Sprintf("same content as row %d", first)
+					return
+				}
+				seenFingerprints[fingerprint] = row.Row
+				var dup models.Post
+				err := coll.FindOne(context.TODO(),
+					bson.M{"fingerprint": fingerprint, "deleted_at": bson.M{"$exists": false}},
+					options.FindOne().SetProjection(bson.M{"_id": 1})).Decode(&dup)
+				if err == nil {
+					res.Status, res.Reason = "skipped", "same content as post "+dup.ID.Hex()
+					return
+				}
+			}
+			categoryIDs, errs := resolveCategoryIDs(row.Post.CategoryIDs)
+			if len(errs) > 0 {
+				res.Reason, res.Details = "Validation failed", errs
+				return
+			}
+			if !dryRun {
+				if err := row.loadMedia(); err != nil {
+					res.Reason = err.Error()
+					return
+				}
+				// ปล่อยรูปของแถวนี้หลังบันทึกเสร็จ
+				defer func() { row.Post.Image = "" }()
+			}
+
+			post := models.Post{
+				ID:          id,
+				User:        strings.TrimSpace(row.Post.User),
+				Title:       strings.TrimSpace(row.Post.Title),
+				Content:     row.Post.Content,
+				Image:       row.Post.Image,
+				Status:      row.Post.Status,
+				Topic:       strings.TrimSpace(row.Post.Topic),
+				Tags:        normalizeTags(row.Post.Tags),
+				CategoryIDs: categoryIDs,
+				Likes:       row.Post.Likes,
+				Comments:    row.Post.Comments,
+				Fingerprint: fingerprint,
+				CreatedAt:   time.Now(),
+				PublishedAt: row.Post.PublishedAt,
+				Version:     1,
+				UpdatedBy:   actor,
+			}
+			if row.Post.CreatedAt != nil {
+				post.CreatedAt = *row.Post.CreatedAt
+			}
+			if post.PublishedAt == nil && (post.Status == "" || post.Status == models.PostStatusPublished) {
+				post.PublishedAt = &post.CreatedAt
+			}
+			if row.Post.Slug != "" && slugPattern.MatchString(row.Post.Slug) {
+				post.Slug = uniqueSlug(row.Post.Slug, id)
+			}
+			if !dryRun {
+				applyImageSet(&post, mediaSourceImport)
+			}
+			applySEO(&post)
+			res.ID = id.Hex()
+
+			if !dryRun {
+				if err := insertPost(&post); err != nil {
+					// โพสต์ไม่ถูกบันทึก (รวมถึงกรณี id ซ้ำที่ข้ามไป) รูปที่เพิ่งเก็บจึงไม่มีใครอ้างถึง
+					discardImageSet(post.ImageSet)
+					if mongo.IsDuplicateKeyError(err) {
+						res.Status, res.Reason = "skipped", "duplicate key: "+err.Error()
+						return
+					}
+					res.Reason = err.Error()
+					return
+				}
+				recordRevision(post, actor, "imported")
+				autoDescribeImageSet(post.ImageSet, usageBilling{Profile: usageProfileEditor, User: actor})
+			}
+			res.Status = "imported"
+		}()
+		counts[res.Status]++
+		results = append(results, res)
+	}
+
+	c.JSON(200, gin.H{
+		"format":   format,
+		"dryRun":   dryRun,
+		"total":    len(rows),
+		"imported": counts["imported"],
+		"skipped":  counts["skipped"],
+		"failed":   counts["error"],
+		"rows":     results,
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func buildBundle(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseZipRowsLoadsMediaPerRow(t *testing.T) {
+	data := buildBundle(t, map[string]string{
+		bundlePostsFile: `{"user":"a","content":"x","image":"media/1.png"}` + "\n" +
+			`{"user":"b","content":"y","image":"media/missing.png"}` + "\n",
+		"media/1.png": "PNGDATA",
+	})
+	rows, err := parseZipRows(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+	if rows[0].Media == nil || rows[0].Post.Image != "media/1.png" {
+		t.Fatalf("row 1 media should stay unread until saved, got %q", rows[0].Post.Image)
+	}
+	if !strings.Contains(rows[1].Err, "missing") {
+		t.Fatalf("row 2 err = %q, want missing media", rows[1].Err)
+	}
+	if err := rows[0].loadMedia(); err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Post.Image != "data:image/png;base64,UE5HREFUQQ==" {
+		t.Fatalf("loaded image = %q", rows[0].Post.Image)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+)
+
+// activePostFilter filter ของโพสต์ที่ยังไม่ถูกลบ (ไม่อยู่ในถังขยะ)
+func activePostFilter(id primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}
+}
+
+// trashRetentionDays จำนวนวันที่เก็บโพสต์ในถังขยะก่อนลบจริง (TRASH_RETENTION_DAYS)
+func trashRetentionDays() int {
+	n, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || n <= 0 {
+		return defaultTrashRetentionDays
+	}
+	return n
+}
+
+// getTrash รายการโพสต์ในถังขยะ พร้อมเวลาที่จะถูกลบถาวร
+func getTrash(c *gin.Context) {
+	opts := options.Find().
+		SetSort(bson.M{"deleted_at": -1}).
+		SetProjection(bson.M{"variants": 0})
+	cursor, err := database.GetCollection("posts").Find(context.TODO(), bson.M{"deleted_at": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	posts := make([]models.Post, 0)
+	_ = cursor.All(context.TODO(), &posts)
+
+	retention := time.Duration(trashRetentionDays()) * 24 * time.Hour
+	items := make([]gin.H, 0, len(posts))
+	for _, p := range withRelativeTime(posts, requestLang(c)) {
+		items = append(items, gin.H{"post": p, "purgeAt": p.DeletedAt.Add(retention)})
+	}
+	c.JSON(200, items)
+}
+
+// restorePost นำโพสต์ออกจากถังขยะ
+func restorePost(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	res, err := database.GetCollection("posts").UpdateOne(context.TODO(),
+		bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}},
+		bson.M{
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+			"$set":   bson.M{"updated_at": time.Now(), "updated_by": requestActor(c)},
+			"$inc":   bson.M{"version": 1},
+		})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		respondError(c, 404, errCodeNotFound, "Post not found in trash")
+		return
+	}
+	post, err := recordPostRevision(id, requestActor(c), "restored from trash")
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	c.Header("ETag", postETag(post.Version))
+	c.JSON(200, post)
+}
+
+// purgePostAssets ลบข้อมูลที่ผูกกับโพสต์ (revision ที่เก็บรูปไว้, ความคิดเห็น, ไลก์, media)
+// รูปแบบ data URL อยู่ในเอกสารโพสต์เองจึงหายไปพร้อมกับโพสต์
+func purgePostAssets(ids []primitive.ObjectID) {
+	filter := bson.M{"post_id": bson.M{"$in": ids}}
+	for _, name := range []string{"post_revisions", "comments", "post_likes"} {
+		if _, err := database.GetCollection(name).DeleteMany(context.TODO(), filter); err != nil {
+			log.Printf("⚠️ ลบ %s ของโพสต์ที่ purge ไม่สำเร็จ: %v", name, err)
+		}
+	}
+	if err := deletePostMedia(ids); err != nil {
+		log.Printf("⚠️ ลบรูปของโพสต์ที่ purge ไม่สำเร็จ: %v", err)
+	}
+}
+
+// purgeExpiredTrash ลบโพสต์ที่อยู่ในถังขยะนานเกิน retention อย่างถาวร
+func purgeExpiredTrash() {
+	cutoff := time.Now().AddDate(0, 0, -trashRetentionDays())
+	coll := database.GetCollection("posts")
+	filter := bson.M{"deleted_at": bson.M{"$lt": cutoff}}
+	cursor, err := coll.Find(context.TODO(), filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		log.Println("⚠️ ค้นหาโพสต์ที่ต้อง purge ไม่สำเร็จ: ", err)
+		return
+	}
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		log.Println("⚠️ ค้นหาโพสต์ที่ต้อง purge ไม่สำเร็จ: ", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	// ลบโพสต์ก่อนทีละตัวโดยยังเช็ค deleted_at แล้วค่อยลบข้อมูลประกอบเฉพาะโพสต์ที่ลบไปจริง
+	// โพสต์ที่ถูกกู้คืนระหว่างนี้จะไม่ match และข้อมูลของมันยังอยู่ครบ
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, r := range rows {
+		res, err := coll.DeleteOne(context.TODO(), bson.M{"_id": r.ID, "deleted_at": bson.M{"$lt": cutoff}})
+		if err != nil {
+			log.Printf("⚠️ purge โพสต์ %s ไม่สำเร็จ: %v", r.ID.Hex(), err)
+			continue
+		}
+		if res.DeletedCount == 1 {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	purgePostAssets(ids)
+	log.Printf("🗑️ ลบโพสต์ในถังขยะที่เกิน %d วันแล้ว %d โพสต์", trashRetentionDays(), len(ids))
+}
+
+// runTrashPurger ทำงานแยกจาก scheduler ของ automation (syncScheduler ล้าง entry ทั้งหมดทุกครั้ง)
+func runTrashPurger() {
+	purgeExpiredTrash()
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purgeExpiredTrash()
+	}
+}
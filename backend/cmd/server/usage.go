@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	usageProfileAutomation = "automation"
+	usageProfileEditor     = "editor"
+)
+
+// usageBilling ผู้ที่ถูกคิดค่าใช้จ่ายของงาน AI ที่ตามมาเบื้องหลัง (เช่น alt text ของรูปที่เพิ่งเก็บ)
+type usageBilling struct {
+	Profile string
+	User    string
+	JobID   string
+}
+
+// modelPrice ราคาต่อ 1M token (USD) หรือต่อรูป
+type modelPrice struct {
+	InputPerMTok  float64 `json:"inputPerMTok"`
+	OutputPerMTok float64 `json:"outputPerMTok"`
+	PerImage      float64 `json:"perImage"`
+}
+
+// ราคาเริ่มต้น override ได้ด้วย MODEL_PRICES_JSON เช่น {"gemini-2.5-flash":{"inputPerMTok":0.3,"outputPerMTok":2.5}}
+var defaultModelPrices = map[string]modelPrice{
+	"gemini-2.5-flash":        {InputPerMTok: 0.30, OutputPerMTok: 2.50},
+	"gemini-2.5-pro":          {InputPerMTok: 1.25, OutputPerMTok: 10.00},
+	"gemini-1.5-flash":        {InputPerMTok: 0.075, OutputPerMTok: 0.30},
+	"imagen-4.0-generate-001": {PerImage: 0.04},
+}
+
+func getModelPrices() map[string]modelPrice {
+	prices := make(map[string]modelPrice, len(defaultModelPrices))
+	for k, v := range defaultModelPrices {
+		prices[k] = v
+	}
+	if raw := os.Getenv("MODEL_PRICES_JSON"); raw != "" {
+		override := map[string]modelPrice{}
+		if err := json.Unmarshal([]byte(raw), &override); err != nil {
+			log.Printf("⚠️ MODEL_PRICES_JSON ไม่ถูกต้อง ใช้ราคาเริ่มต้นแทน: %v", err)
+			return prices
+		}
+		for k, v := range override {
+			prices[k] = v
+		}
+	}
+	return prices
+}
+
+// recordUsage คำนวณค่าใช้จ่ายจากตารางราคาแล้วบันทึกลง collection usage
+func recordUsage(rec models.UsageRecord) {
+	price := getModelPrices()[rec.Model]
+	rec.CostUSD = float64(rec.PromptTokens)/1e6*price.InputPerMTok +
+		float64(rec.CandidateTokens)/1e6*price.OutputPerMTok +
+		float64(rec.Images)*price.PerImage
+
+	now := time.Now()
+	rec.ID = primitive.NewObjectID()
+	rec.Day = now.Format("2006-01-02")
+	rec.CreatedAt = now
+	if _, err := database.GetCollection("usage").InsertOne(context.TODO(), rec); err != nil {
+		log.Println("⚠️ บันทึก usage ไม่สำเร็จ: ", err)
+	}
+}
+
+// jobID โยง usage กับ Generation.JobID ของโพสต์ ("" ถ้าไม่ได้เกิดจากการสร้างโพสต์)
+func recordTextUsage(gen textGeneration, profile, user, jobID string) {
+	recordUsage(models.UsageRecord{
+		JobID:           jobID,
+		Kind:            "text",
+		Model:           gen.Model,
+		Profile:         profile,
+		User:            user,
+		PromptTokens:    gen.PromptTokens,
+		CandidateTokens: gen.CandidateTokens,
+	})
+}
+
+func recordImageUsage(profile, user, jobID string) {
+	recordUsage(models.UsageRecord{
+		JobID:   jobID,
+		Kind:    "image",
+		Model:   getImageModel(),
+		Profile: profile,
+		User:    user,
+		Images:  1,
+	})
+}
+
+// monthToDateCost ค่าใช้จ่ายรวมตั้งแต่ต้นเดือนปัจจุบัน
+func monthToDateCost() (float64, error) {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	cursor, err := database.GetCollection("usage").Aggregate(context.TODO(), bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": start}}},
+		bson.M{"$group": bson.M{"_id": nil, "cost": bson.M{"$sum": "$cost_usd"}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var out []struct {
+		Cost float64 `bson:"cost"`
+	}
+	if err := cursor.All(context.TODO(), &out); err != nil {
+		return 0, err
+	}
+	if len(out) == 0 {
+		return 0, nil
+	}
+	return out[0].Cost, nil
+}
+
+// budgetExceeded true เมื่อใช้เกินงบรายเดือน (MonthlyBudgetUSD = 0 คือไม่จำกัด)
+func budgetExceeded(config models.AutoConfig) bool {
+	if config.MonthlyBudgetUSD <= 0 {
+		return false
+	}
+	spent, err := monthToDateCost()
+	if err != nil {
+		log.Println("⚠️ ตรวจงบประมาณไม่สำเร็จ: ", err)
+		return false
+	}
+	return spent >= config.MonthlyBudgetUSD
+}
+
+// getUsage สรุป usage ตามช่วงวัน จัดกลุ่มด้วย groupBy=day|profile|user|model
+func getUsage(c *gin.Context) {
+	groupField := map[string]string{
+		"day":     "$day",
+		"profile": "$profile",
+		"user":    "$user",
+		"model":   "$model",
+	}
+	groupBy := c.DefaultQuery("groupBy", "day")
+	field, ok := groupField[groupBy]
+	if !ok {
+		respondError(c, 400, errCodeValidationFailed, "groupBy must be day, profile, user or model")
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := now
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, now.Location())
+		if err != nil {
+			respondError(c, 400, errCodeValidationFailed, "from must be YYYY-MM-DD")
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, now.Location())
+		if err != nil {
+			respondError(c, 400, errCodeValidationFailed, "to must be YYYY-MM-DD")
+			return
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+
+	cursor, err := database.GetCollection("usage").Aggregate(context.TODO(), bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}},
+		bson.M{"$group": bson.M{
+			"_id":              field,
+			"calls":            bson.M{"$sum": 1},
+			"prompt_tokens":    bson.M{"$sum": "$prompt_tokens"},
+			"candidate_tokens": bson.M{"$sum": "$candidate_tokens"},
+			"images":           bson.M{"$sum": "$images"},
+			"cost_usd":         bson.M{"$sum": "$cost_usd"},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	})
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	rows := make([]models.UsageSummary, 0)
+	if err := cursor.All(context.TODO(), &rows); err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	spent, _ := monthToDateCost()
+
+	c.JSON(200, gin.H{
+		"groupBy":          groupBy,
+		"from":             from,
+		"to":               to,
+		"rows":             rows,
+		"monthToDateUSD":   spent,
+		"monthlyBudgetUSD": config.MonthlyBudgetUSD,
+		"paused":           config.MonthlyBudgetUSD > 0 && spent >= config.MonthlyBudgetUSD,
+	})
+}
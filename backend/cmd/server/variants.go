@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	maxContentVariants = 5
+	variantConcurrency = 3
+)
+
+// คำ/วลีที่ไม่ตรงกับ brand voice (override ได้ด้วย BRAND_VOICE_BANNED คั่นด้วย comma)
+var defaultBrandVoiceBanned = []string{
+	"ถูกที่สุด",
+	"ดีที่สุดในโลก",
+	"การันตี 100%",
+	"รับประกันผล",
+	"ด่วนที่สุด",
+	"!!!",
+}
+
+type variantScore struct {
+	Chars       int      `json:"chars"`
+	Words       int      `json:"words"`
+	WithinLimit bool     `json:"withinLimit"`
+	Violations  []string `json:"violations"`
+	Score       float64  `json:"score"`
+}
+
+type contentVariant struct {
+	Content string       `json:"content"`
+	Score   variantScore `json:"score"`
+	Error   string       `json:"error,omitempty"`
+}
+
+func getBrandVoiceBanned() []string {
+	v := strings.TrimSpace(os.Getenv("BRAND_VOICE_BANNED"))
+	if v == "" {
+		return defaultBrandVoiceBanned
+	}
+	list := make([]string, 0)
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// scoreVariant ให้คะแนนเนื้อหา 0-100 จากความยาวเทียบกับ wordLimit และจำนวนคำต้องห้าม
+// หมายเหตุ: ภาษาไทยไม่เว้นวรรคระหว่างคำ จำนวน Words จึงเป็นค่าประมาณจากช่องว่าง
+func scoreVariant(content string, wordLimit int) variantScore {
+	s := variantScore{
+		Chars:      utf8.RuneCountInString(content),
+		Words:      len(strings.Fields(content)),
+		Violations: make([]string, 0),
+	}
+	s.WithinLimit = wordLimit <= 0 || s.Words <= wordLimit
+
+	lower := strings.ToLower(content)
+	for _, banned := range getBrandVoiceBanned() {
+		if strings.Contains(lower, strings.ToLower(banned)) {
+			s.Violations = append(s.Violations, banned)
+		}
+	}
+
+	score := 100.0
+	if !s.WithinLimit {
+		score -= 30
+	}
+	if s.Chars < 80 {
+		score -= 20
+	}
+	score -= float64(len(s.Violations)) * 15
+	if score < 0 {
+		score = 0
+	}
+	s.Score = score
+	return s
+}
+
+// generateContentVariants เรียก Gemini n ครั้งแบบขนาน (จำกัด concurrency) แล้วเรียงตามคะแนน
+func generateContentVariants(topic, basePrompt string, wordLimit, n int, user string) ([]contentVariant, error) {
+	if n < 1 {
+		n = 1
+	}
+	if n > maxContentVariants {
+		n = maxContentVariants
+	}
+
+	variants := make([]contentVariant, n)
+	sem := make(chan struct{}, variantConcurrency)
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			gen, err := generateText(topic, basePrompt, wordLimit)
+			if err != nil {
+				variants[i] = contentVariant{Error: err.Error()}
+				return
+			}
+			recordTextUsage(gen, usageProfileEditor, user, "")
+			variants[i] = contentVariant{Content: gen.Content, Score: scoreVariant(gen.Content, wordLimit)}
+		}(i)
+	}
+	wg.Wait()
+
+	ok := make([]contentVariant, 0, n)
+	var firstErr string
+	for _, v := range variants {
+		if v.Error != "" {
+			if firstErr == "" {
+				firstErr = v.Error
+			}
+			continue
+		}
+		ok = append(ok, v)
+	}
+	if len(ok) == 0 {
+		return nil, fmt.Errorf("all variants failed: %s", firstErr)
+	}
+
+	sort.SliceStable(ok, func(a, b int) bool { return ok[a].Score.Score > ok[b].Score.Score })
+	return ok, nil
+}
+
+// saveVariants บันทึกเนื้อหาหลายแบบ (A/B) ผูกกับโพสต์เดิม
+func saveVariants(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Variants []struct {
+			Content string `json:"content" binding:"required,max=20000"`
+			Image   string `json:"image" binding:"max=15728640"`
+		} `json:"variants" binding:"required,dive"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if len(req.Variants) < 2 || len(req.Variants) > maxContentVariants {
+		respondError(c, 400, errCodeValidationFailed, fmt.Sprintf("variants must contain 2-%d items", maxContentVariants))
+		return
+	}
+
+	variants := make([]models.PostVariant, 0, len(req.Variants))
+	for i, v := range req.Variants {
+		content := strings.TrimSpace(v.Content)
+		if content == "" {
+			respondError(c, 400, errCodeValidationFailed, fmt.Sprintf("variant %d content is empty", i+1))
+			return
+		}
+		variants = append(variants, models.PostVariant{
+			Key:     string(rune('A' + i)),
+			Content: content,
+			Image:   strings.TrimSpace(v.Image),
+		})
+	}
+
+	res, err := database.GetCollection("posts").UpdateOne(
+		context.TODO(),
+		activePostFilter(id),
+		touchPost(bson.M{"variants": variants}, requestActor(c)),
+	)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	c.JSON(200, gin.H{"variants": variants})
+}
+
+// trackVariant นับ impression/click ของแต่ละ variant (เว็บสาธารณะเรียกผ่าน /public/v1)
+func trackVariant(c *gin.Context) {
+	id, ok := findPublishedPostID(c.Param("slug"))
+	if !ok {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+
+	field := ""
+	switch c.Query("event") {
+	case "impression":
+		field = "variants.$.impressions"
+	case "click":
+		field = "variants.$.clicks"
+	default:
+		respondError(c, 400, errCodeValidationFailed, "event must be impression or click")
+		return
+	}
+
+	res, err := database.GetCollection("posts").UpdateOne(
+		context.TODO(),
+		bson.M{"_id": id, "variants.key": c.Param("key")},
+		bson.M{"$inc": bson.M{field: 1}},
+	)
+	if err != nil {
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		respondError(c, 404, errCodeNotFound, "Variant not found")
+		return
+	}
+	c.JSON(200, gin.H{"status": "tracked"})
+}
+
+// pickVariantWinner ใช้ variant ที่เลือกเป็นเนื้อหาหลักของโพสต์
+// เขียนทับเฉพาะเมื่อ version ยังเป็นฉบับที่อ่านมา (และตรงกับ If-Match ถ้าส่งมา)
+func pickVariantWinner(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	version, conditional, ok := expectedVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	coll := database.GetCollection("posts")
+	var post models.Post
+	if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&post); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	if conditional && version != post.Version {
+		respondVersionConflict(c, post)
+		return
+	}
+
+	key := c.Param("key")
+	found := false
+	for i := range post.Variants {
+		post.Variants[i].IsWinner = post.Variants[i].Key == key
+		if post.Variants[i].IsWinner {
+			found = true
+			post.Content = post.Variants[i].Content
+			if post.Variants[i].Image != "" {
+				post.Image = post.Variants[i].Image
+			}
+		}
+	}
+	if !found {
+		respondError(c, 404, errCodeNotFound, "Variant not found")
+		return
+	}
+
+	ensureBaselineRevision(id)
+	set := imageFields(post.Image, mediaSourceUpload, id)
+	set["content"] = post.Content
+	set["fingerprint"] = contentFingerprint(post.Content)
+	set["variants"] = post.Variants
+	res, err := coll.UpdateOne(context.TODO(), versionFilter(id, post.Version), touchPost(set, requestActor(c)))
+	if err != nil {
+		discardStoredImage(set)
+		respondError(c, 500, errCodeInternal, err.Error())
+		return
+	}
+	if res.MatchedCount == 0 {
+		discardStoredImage(set)
+		// โพสต์ถูกแก้หรือย้ายไปถังขยะระหว่างนี้ ไม่เขียนทับ
+		var current models.Post
+		if err := coll.FindOne(context.TODO(), activePostFilter(id)).Decode(&current); err != nil {
+			respondError(c, 404, errCodeNotFound, "Post not found")
+			return
+		}
+		respondVersionConflict(c, current)
+		return
+	}
+	refreshPostSEO(id)
+	autoDescribeImageSet(storedImageSet(set), usageBilling{Profile: usageProfileEditor, User: requestActor(c)})
+	if updated, err := recordPostRevision(id, requestActor(c), "variant "+key+" picked as winner"); err == nil {
+		post = updated
+	}
+	c.Header("ETag", postETag(post.Version))
+	c.JSON(200, post)
+}
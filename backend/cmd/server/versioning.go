@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// postETag ETag ของโพสต์ฝั่ง admin อ้างอิงจาก version (ไม่ใช่ hash ของเนื้อหาแบบ public API)
+func postETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// expectedVersion อ่าน version ที่ client เห็นล่าสุดจาก If-Match หรือฟิลด์ version ใน body
+// conditional = false แปลว่า client ไม่ได้ส่งมา (client เก่า) จะแก้ไขแบบไม่ตรวจ version
+func expectedVersion(c *gin.Context, bodyVersion *int64) (version int64, conditional bool, ok bool) {
+	if h := strings.TrimSpace(c.GetHeader("If-Match")); h != "" && h != "*" {
+		tag := strings.Trim(strings.TrimPrefix(h, "W/"), `"`)
+		v, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil || v < 0 {
+			respondError(c, 400, errCodeValidationFailed, "If-Match must be a post version ETag")
+			return 0, false, false
+		}
+		return v, true, true
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, true, true
+	}
+	return 0, false, true
+}
+
+// versionFilter filter ของโพสต์ที่ยังอยู่ที่ version นี้ (โพสต์เก่าที่ไม่มีฟิลด์ version ถือเป็น 0)
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	filter := activePostFilter(id)
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// touchPost สร้าง update ที่เพิ่ม version และบันทึกผู้แก้ไขล่าสุด ใช้กับทุกการแก้ไขเนื้อหาโพสต์
+func touchPost(set bson.M, actor string) bson.M {
+	set["updated_at"] = time.Now()
+	set["updated_by"] = actor
+	return bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+}
+
+// respondVersionConflict ตอบ 409 พร้อมสำเนาล่าสุดบน server ให้ client นำไป merge เอง
+func respondVersionConflict(c *gin.Context, current models.Post) {
+	current.Time = relativeTime(postPublishedAt(current), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(current.Version))
+	c.AbortWithStatusJSON(409, gin.H{
+		"error":   "Post was modified by someone else",
+		"code":    errCodeConflict,
+		"current": current,
+	})
+}
+
+// getPost โพสต์เดียวสำหรับหน้าแก้ไข พร้อม ETag ไว้ส่งกลับมาใน If-Match
+func getPost(c *gin.Context) {
+	id, ok := paramObjectID(c, "id")
+	if !ok {
+		return
+	}
+	var post models.Post
+	if err := database.GetCollection("posts").FindOne(context.TODO(), activePostFilter(id)).Decode(&post); err != nil {
+		respondError(c, 404, errCodeNotFound, "Post not found")
+		return
+	}
+	post.Time = relativeTime(postPublishedAt(post), time.Now(), requestLang(c))
+	c.Header("ETag", postETag(post.Version))
+	c.JSON(200, post)
+}
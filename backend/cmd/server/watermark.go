@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"image"
+	"image/color"
+	stddraw "image/draw"
+	"image/png"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+const (
+	watermarkDefaultPosition  = "bottom-right"
+	watermarkDefaultOpacity   = 0.8
+	watermarkDefaultLogoScale = 0.15
+	// ขนาดตัวอักษรของ caption เทียบกับความกว้างรูป
+	watermarkCaptionScale = 0.035
+)
+
+// Noto Sans Thai (SIL OFL 1.1) ดูสัญญาอนุญาตใน fonts/OFL.txt
+//
+//go:embed fonts/NotoSansThai-Regular.ttf
+var watermarkFontData []byte
+
+var (
+	watermarkFontOnce sync.Once
+	watermarkFont     *font.Font
+)
+
+// watermarkDefaults เติมค่าเริ่มต้นให้ฟิลด์ที่ไม่ได้ตั้ง
+func watermarkDefaults(wm models.Watermark) models.Watermark {
+	if wm.Position == "" {
+		wm.Position = watermarkDefaultPosition
+	}
+	if wm.Opacity == 0 {
+		wm.Opacity = watermarkDefaultOpacity
+	}
+	if wm.LogoScale == 0 {
+		wm.LogoScale = watermarkDefaultLogoScale
+	}
+	return wm
+}
+
+// loadWatermarkFont คืน *font.Font ที่ใช้ร่วมกันได้หลาย goroutine (font.Face ใช้ร่วมกันไม่ได้)
+func loadWatermarkFont() *font.Font {
+	watermarkFontOnce.Do(func() {
+		face, err := font.ParseTTF(bytes.NewReader(watermarkFontData))
+		if err != nil {
+			log.Println("⚠️ โหลดฟอนต์ watermark ไม่สำเร็จ: ", err)
+			return
+		}
+		watermarkFont = face.Font
+	})
+	return watermarkFont
+}
+
+// shapedCaption ข้อความที่ผ่าน shaping แล้ว (ตำแหน่งสระบน/ล่างและวรรณยุกต์ถูกจัดตาม GPOS ของฟอนต์)
+type shapedCaption struct {
+	runs    []shaping.Output
+	width   fixed.Int26_6
+	ascent  fixed.Int26_6
+	descent fixed.Int26_6
+}
+
+// singleFontmap ใช้ฟอนต์เดียวกับทุกตัวอักษร (Noto Sans Thai มีทั้งไทยและละติน)
+type singleFontmap struct{ face *font.Face }
+
+func (m singleFontmap) ResolveFace(rune) *font.Face { return m.face }
+
+// shapeCaption แบ่ง run ตาม script แล้ว shape ด้วย HarfBuzz
+// font.Drawer ของ x/image วางทีละ glyph ตาม advance จึงวางสระ/วรรณยุกต์ไทยซ้อนกันผิดตำแหน่ง
+func shapeCaption(f *font.Font, text string, size float64) shapedCaption {
+	face := font.NewFace(f)
+	runes := []rune(text)
+	input := shaping.Input{
+		Text:      runes,
+		RunEnd:    len(runes),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      fixed.Int26_6(math.Round(size * 64)),
+		Language:  language.NewLanguage("th"),
+	}
+
+	var out shapedCaption
+	var seg shaping.Segmenter
+	var shaper shaping.HarfbuzzShaper
+	for _, in := range seg.Split(input, singleFontmap{face}) {
+		run := shaper.Shape(in)
+		out.runs = append(out.runs, run)
+		out.width += run.Advance
+		out.ascent = max(out.ascent, run.LineBounds.Ascent)
+		out.descent = max(out.descent, -run.LineBounds.Descent)
+	}
+	return out
+}
+
+// drawCaption วาด outline ของทุก glyph ลงกรอบที่มุมซ้ายบนอยู่ที่ (x, y)
+func drawCaption(dst *image.RGBA, sc shapedCaption, x, y int, src image.Image) {
+	w, h := sc.width.Ceil(), (sc.ascent + sc.descent).Ceil()
+	if w <= 0 || h <= 0 {
+		return
+	}
+	r := vector.NewRasterizer(w, h)
+	baseline := fixedToFloat(sc.ascent)
+	var dot float32
+	for i := range sc.runs {
+		run := &sc.runs[i]
+		scale := fixedToFloat(run.Size) / float32(run.Face.Upem())
+		for _, g := range run.Glyphs {
+			if outline, ok := run.Face.GlyphData(g.GlyphID).(font.GlyphOutline); ok {
+				gx, gy := dot+fixedToFloat(g.XOffset), baseline-fixedToFloat(g.YOffset)
+				// แกน y ของฟอนต์ชี้ขึ้น แต่ของรูปชี้ลง
+				pt := func(p ot.SegmentPoint) (float32, float32) { return gx + p.X*scale, gy - p.Y*scale }
+				for _, s := range outline.Segments {
+					switch s.Op {
+					case ot.SegmentOpMoveTo:
+						r.MoveTo(pt(s.Args[0]))
+					case ot.SegmentOpLineTo:
+						r.LineTo(pt(s.Args[0]))
+					case ot.SegmentOpQuadTo:
+						bx, by := pt(s.Args[0])
+						cx, cy := pt(s.Args[1])
+						r.QuadTo(bx, by, cx, cy)
+					case ot.SegmentOpCubeTo:
+						bx, by := pt(s.Args[0])
+						cx, cy := pt(s.Args[1])
+						dx, dy := pt(s.Args[2])
+						r.CubeTo(bx, by, cx, cy, dx, dy)
+					}
+				}
+				r.ClosePath()
+			}
+			dot += fixedToFloat(g.Advance)
+		}
+	}
+	r.Draw(dst, image.Rect(x, y, x+w, y+h), src, image.Point{})
+}
+
+func fixedToFloat(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
+
+// watermarkImage ทับโลโก้/ข้อความบนรูป data URL ถ้าเปิดไว้สำหรับ profile นี้
+// ถ้าทับไม่สำเร็จจะคืนรูปเดิม (ไม่ให้การสร้างโพสต์ล้มเพราะ watermark)
+func watermarkImage(dataURL, profile string) string {
+	var config models.AutoConfig
+	_ = database.GetCollection("auto_config").FindOne(context.TODO(), bson.M{}).Decode(&config)
+	wm := config.Watermark
+	if !slices.Contains(wm.Profiles, profile) || (wm.LogoMediaID == "" && strings.TrimSpace(wm.Caption) == "") {
+		return dataURL
+	}
+	_, data, ok := parseDataURL(dataURL)
+	if !ok {
+		return dataURL
+	}
+	out, err := applyWatermark(data, watermarkDefaults(wm))
+	if err != nil {
+		log.Println("⚠️ ใส่ watermark ไม่สำเร็จ: ", err)
+		return dataURL
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(out)
+}
+
+// applyWatermark วางโลโก้และ caption เรียงกันในแนวตั้งที่มุมตาม Position แล้วเข้ารหัสเป็น PNG
+func applyWatermark(data []byte, wm models.Watermark) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	stddraw.Draw(dst, dst.Bounds(), src, b.Min, stddraw.Src)
+
+	var logo image.Image
+	if wm.LogoMediaID != "" {
+		if logo, err = loadWatermarkLogo(wm.LogoMediaID); err != nil {
+			return nil, err
+		}
+		logo = resizeImage(logo, int(math.Max(1, float64(b.Dx())*wm.LogoScale)))
+	}
+
+	var text *shapedCaption
+	caption := strings.TrimSpace(wm.Caption)
+	if caption != "" {
+		if f := loadWatermarkFont(); f != nil {
+			sc := shapeCaption(f, caption, math.Max(12, float64(b.Dx())*watermarkCaptionScale))
+			text = &sc
+		}
+	}
+
+	// ขนาดของกลุ่มโลโก้ + caption
+	gap := 0
+	blockW, blockH := 0, 0
+	if logo != nil {
+		blockW, blockH = logo.Bounds().Dx(), logo.Bounds().Dy()
+	}
+	var textW, textH int
+	if text != nil {
+		textW = text.width.Ceil()
+		textH = (text.ascent + text.descent).Ceil()
+		if logo != nil {
+			gap = textH / 3
+		}
+		blockW = max(blockW, textW)
+		blockH += gap + textH
+	}
+	if blockW == 0 {
+		return data, nil
+	}
+
+	x0, y0, align := watermarkOrigin(wm.Position, wm.Margin, b.Dx(), b.Dy(), blockW, blockH)
+	alpha := uint8(math.Round(wm.Opacity * 255))
+	mask := image.NewUniform(color.Alpha{A: alpha})
+
+	y := y0
+	if logo != nil {
+		lb := logo.Bounds()
+		x := x0 + alignOffset(align, blockW, lb.Dx())
+		stddraw.DrawMask(dst, image.Rect(x, y, x+lb.Dx(), y+lb.Dy()), logo, lb.Min, mask, image.Point{}, stddraw.Over)
+		y += lb.Dy() + gap
+	}
+	if text != nil {
+		x := x0 + alignOffset(align, blockW, textW)
+		// เงาจางๆ ช่วยให้อ่านออกบนพื้นสีอ่อน
+		shadow := max(1, textH/20)
+		for _, layer := range []struct {
+			c      color.Color
+			offset int
+		}{
+			{color.NRGBA{A: alpha / 2}, shadow},
+			{color.NRGBA{R: 255, G: 255, B: 255, A: alpha}, 0},
+		} {
+			drawCaption(dst, *text, x+layer.offset, y+layer.offset, image.NewUniform(layer.c))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// watermarkOrigin มุมซ้ายบนของกลุ่ม watermark และการจัดแนวภายในกลุ่ม (-1 ซ้าย, 0 กลาง, 1 ขวา)
+func watermarkOrigin(position string, margin, w, h, blockW, blockH int) (int, int, int) {
+	switch position {
+	case "top-left":
+		return margin, margin, -1
+	case "top-right":
+		return w - margin - blockW, margin, 1
+	case "bottom-left":
+		return margin, h - margin - blockH, -1
+	case "center":
+		return (w - blockW) / 2, (h - blockH) / 2, 0
+	default:
+		return w - margin - blockW, h - margin - blockH, 1
+	}
+}
+
+func alignOffset(align, blockW, itemW int) int {
+	switch align {
+	case 1:
+		return blockW - itemW
+	case 0:
+		return (blockW - itemW) / 2
+	}
+	return 0
+}
+
+// loadWatermarkLogo อ่านไฟล์ต้นฉบับของโลโก้จาก media (ควรเป็น PNG พื้นโปร่งใส)
+func loadWatermarkLogo(mediaID string) (image.Image, error) {
+	id, err := primitive.ObjectIDFromHex(mediaID)
+	if err != nil {
+		return nil, err
+	}
+	_, data, ok := loadMediaOriginal(id)
+	if !ok {
+		return nil, errMediaNotFound
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
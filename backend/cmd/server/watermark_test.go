@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"backend/internal/models"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// markTop ขอบบนของ glyph สุดท้ายเทียบกับ baseline (ค่ามากอยู่สูงกว่า)
+func markTop(t *testing.T, text string) fixed.Int26_6 {
+	t.Helper()
+	sc := shapeCaption(loadWatermarkFont(), text, 48)
+	if len(sc.runs) == 0 {
+		t.Fatalf("shapeCaption(%q) returned no runs", text)
+	}
+	glyphs := sc.runs[len(sc.runs)-1].Glyphs
+	g := glyphs[len(glyphs)-1]
+	return g.YOffset + g.YBearing
+}
+
+func TestShapeCaptionStacksThaiMarks(t *testing.T) {
+	if loadWatermarkFont() == nil {
+		t.Fatal("embedded watermark font failed to load")
+	}
+	// ไม้เอกบนสระอีต้องถูกยกขึ้นเหนือสระ ไม่ซ้อนทับที่ความสูงเดียวกับ "ท่"
+	if alone, stacked := markTop(t, "ท่"), markTop(t, "ที่"); stacked <= alone {
+		t.Fatalf("tone mark top: ที่ = %v, ท่ = %v, want ที่ higher", stacked, alone)
+	}
+}
+
+func TestApplyWatermarkThaiCaption(t *testing.T) {
+	bg := color.RGBA{R: 40, G: 80, B: 120, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+1], src.Pix[i+2], src.Pix[i+3] = bg.R, bg.G, bg.B, bg.A
+	}
+	var in bytes.Buffer
+	if err := png.Encode(&in, src); err != nil {
+		t.Fatal(err)
+	}
+
+	wm := watermarkDefaults(models.Watermark{Caption: "ร้านกาแฟริมน้ำ ที่นี่", Margin: 10})
+	out, err := applyWatermark(in.Bytes(), wm)
+	if err != nil {
+		t.Fatalf("applyWatermark: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if img.Bounds() != src.Bounds() {
+		t.Fatalf("bounds = %v, want %v", img.Bounds(), src.Bounds())
+	}
+
+	// caption อยู่มุมขวาล่าง ครึ่งบนของรูปต้องไม่เปลี่ยน
+	changed := func(r image.Rectangle) int {
+		n := 0
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if color.RGBAModel.Convert(img.At(x, y)) != bg {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	if n := changed(image.Rect(0, 0, 400, 100)); n != 0 {
+		t.Fatalf("%d pixels changed in the top half, want 0", n)
+	}
+	if n := changed(image.Rect(200, 150, 400, 200)); n == 0 {
+		t.Fatal("caption was not drawn in the bottom-right corner")
+	}
+}
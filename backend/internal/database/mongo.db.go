@@ -53,6 +53,17 @@ func createIndexes() {
 	if err != nil {
 		log.Println("⚠️ ไม่สามารถสร้าง Index ได้ (อาจมีอยู่แล้ว): ", err)
 	}
+
+	// slug ต้องไม่ซ้ำกัน (เฉพาะโพสต์ที่มี slug แล้ว)
+	slugIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"slug": bson.M{"$type": "string"},
+		}),
+	}
+	if _, err := coll.Indexes().CreateOne(ctx, slugIndex); err != nil {
+		log.Println("⚠️ ไม่สามารถสร้าง Index slug ได้: ", err)
+	}
 }
 
 // GetCollection ฟังก์ชันช่วยดึง Collection ที่ต้องการใช้งาน
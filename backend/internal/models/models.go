@@ -11,18 +11,313 @@ type Post struct {
 	User      string             `bson:"user" json:"user"`
 	Content   string             `bson:"content" json:"content"`
 	Image     string             `bson:"image" json:"image"`
-	Time      string             `bson:"time" json:"time"`
-	Likes     string             `bson:"likes" json:"likes"`
-	Comments  string             `bson:"comments" json:"comments"`
+	// ImageSet ขนาดย่อของรูปสำหรับ srcset (nil = รูปภายนอกหรือ data URL ที่ยังไม่ผ่าน pipeline)
+	ImageSet  *ImageSet          `bson:"image_set,omitempty" json:"imageSet,omitempty"`
+	// Deprecated: Time คำนวณจาก PublishedAt/CreatedAt ตอนอ่าน ไม่ได้เก็บลงฐานข้อมูลแล้ว
+	Time      string             `bson:"-" json:"time"`
+	// Likes/Comments เป็นตัวนับ อัปเดตผ่าน endpoint เฉพาะเท่านั้น
+	Likes     int64              `bson:"like_count" json:"likes"`
+	Comments  int64              `bson:"comment_count" json:"comments"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	Variants  []PostVariant      `bson:"variants,omitempty" json:"variants,omitempty"`
+	// Status ว่าง = published (โพสต์เก่าก่อนมีระบบ moderation)
+	Status     string      `bson:"status,omitempty" json:"status,omitempty"`
+	Moderation *Moderation `bson:"moderation,omitempty" json:"moderation,omitempty"`
+	// Fingerprint SimHash ของ Content ใช้ตรวจโพสต์ซ้ำ
+	Fingerprint string     `bson:"fingerprint,omitempty" json:"fingerprint,omitempty"`
+	Title       string     `bson:"title,omitempty" json:"title,omitempty"`
+	Slug        string     `bson:"slug,omitempty" json:"slug,omitempty"`
+	SEO         *SEO       `bson:"seo,omitempty" json:"seo,omitempty"`
+	Topic       string     `bson:"topic,omitempty" json:"topic,omitempty"`
+	// Tags ตัวพิมพ์เล็กทั้งหมด (ดู normalizeTag), CategoryIDs อ้างถึง collection categories
+	Tags        []string             `bson:"tags,omitempty" json:"tags,omitempty"`
+	CategoryIDs []primitive.ObjectID `bson:"category_ids,omitempty" json:"categoryIds,omitempty"`
+	PublishedAt *time.Time `bson:"published_at,omitempty" json:"publishedAt,omitempty"`
+	// Generation ข้อมูลที่ใช้สร้างโพสต์ด้วย AI (nil = โพสต์ที่เขียนเอง)
+	Generation *Generation `bson:"generation,omitempty" json:"generation,omitempty"`
+	// Version เพิ่มทีละ 1 ทุกครั้งที่แก้ไข ใช้กับ If-Match กันการเขียนทับกัน
+	Version   int64      `bson:"version" json:"version"`
+	UpdatedAt *time.Time `bson:"updated_at,omitempty" json:"updatedAt,omitempty"`
+	UpdatedBy string     `bson:"updated_by,omitempty" json:"updatedBy,omitempty"`
+	// DeletedAt != nil คือโพสต์อยู่ในถังขยะ รอ purge ตาม TRASH_RETENTION_DAYS
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"deletedBy,omitempty"`
+	// token ภาษาไทยที่ตัดคำแล้ว (คั่นด้วยช่องว่าง) สำหรับ text index
+	SearchTitle   string `bson:"search_title,omitempty" json:"-"`
+	SearchBody    string `bson:"search_body,omitempty" json:"-"`
+	SearchVersion int    `bson:"search_version,omitempty" json:"-"`
+}
+
+// SEO meta description และข้อมูล Open Graph/Twitter card ของโพสต์
+type SEO struct {
+	Description        string `bson:"description" json:"description"`
+	CanonicalURL       string `bson:"canonical_url" json:"canonicalUrl"`
+	OGType             string `bson:"og_type" json:"ogType"`
+	OGTitle            string `bson:"og_title" json:"ogTitle"`
+	OGDescription      string `bson:"og_description" json:"ogDescription"`
+	OGImage            string `bson:"og_image,omitempty" json:"ogImage,omitempty"`
+	OGImageAlt         string `bson:"og_image_alt,omitempty" json:"ogImageAlt,omitempty"`
+	TwitterCard        string `bson:"twitter_card" json:"twitterCard"`
+	TwitterTitle       string `bson:"twitter_title" json:"twitterTitle"`
+	TwitterDescription string `bson:"twitter_description" json:"twitterDescription"`
+	TwitterImage       string `bson:"twitter_image,omitempty" json:"twitterImage,omitempty"`
+	TwitterImageAlt    string `bson:"twitter_image_alt,omitempty" json:"twitterImageAlt,omitempty"`
+}
+
+const (
+	PostStatusPublished = "published"
+	PostStatusReview    = "review"
+	PostStatusRejected  = "rejected"
+	// PostStatusArchived เก็บไว้แต่ไม่แสดงบนหน้าเว็บ ต่างจากถังขยะตรงที่ไม่ถูก purge
+	PostStatusArchived  = "archived"
+)
+
+// SafetyRating ผลการประเมินความปลอดภัยจาก Gemini
+type SafetyRating struct {
+	Category    string `bson:"category" json:"category"`
+	Probability string `bson:"probability" json:"probability"`
+	Blocked     bool   `bson:"blocked" json:"blocked"`
+}
+
+// Moderation ผลการตรวจเนื้อหาก่อนเผยแพร่
+type Moderation struct {
+	Flagged       bool           `bson:"flagged" json:"flagged"`
+	Reasons       []string       `bson:"reasons" json:"reasons"`
+	FinishReason  string         `bson:"finish_reason,omitempty" json:"finishReason,omitempty"`
+	SafetyRatings []SafetyRating `bson:"safety_ratings,omitempty" json:"safetyRatings,omitempty"`
+	CheckedAt     time.Time      `bson:"checked_at" json:"checkedAt"`
+	ReviewedBy    string         `bson:"reviewed_by,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedAt    *time.Time     `bson:"reviewed_at,omitempty" json:"reviewedAt,omitempty"`
+}
+
+// Generation ที่มาของโพสต์ที่สร้างด้วย AI: input ทั้งหมด (ใช้สร้างใหม่ด้วยค่าเดิมได้),
+// model ที่ใช้ เวลาที่ใช้ token และผลความปลอดภัย สำหรับ audit และเทียบว่า prompt ไหนได้ผลดี
+type Generation struct {
+	Topic       string `bson:"topic" json:"topic"`
+	BasePrompt  string `bson:"base_prompt" json:"basePrompt"`
+	WordLimit   int    `bson:"word_limit" json:"wordLimit"`
+	ImagePrompt string `bson:"image_prompt" json:"imagePrompt"`
+
+	// Source automation หรือ editor, JobID ใช้โยงกับ usage และ bulk job ที่สร้าง/แก้โพสต์นี้
+	Source string `bson:"source,omitempty" json:"source,omitempty"`
+	JobID  string `bson:"job_id,omitempty" json:"jobId,omitempty"`
+
+	TextModel       string         `bson:"text_model,omitempty" json:"textModel,omitempty"`
+	FinishReason    string         `bson:"finish_reason,omitempty" json:"finishReason,omitempty"`
+	SafetyRatings   []SafetyRating `bson:"safety_ratings,omitempty" json:"safetyRatings,omitempty"`
+	PromptTokens    int64          `bson:"prompt_tokens,omitempty" json:"promptTokens,omitempty"`
+	CandidateTokens int64          `bson:"candidate_tokens,omitempty" json:"candidateTokens,omitempty"`
+	TextLatencyMS   int64          `bson:"text_latency_ms,omitempty" json:"textLatencyMs,omitempty"`
+	// TextAttempts จำนวนครั้งที่สร้างเนื้อหา (มากกว่า 1 เมื่อสร้างใหม่เพราะซ้ำกับโพสต์เดิม)
+	TextAttempts int `bson:"text_attempts,omitempty" json:"textAttempts,omitempty"`
+
+	ImageModel     string `bson:"image_model,omitempty" json:"imageModel,omitempty"`
+	ImageLatencyMS int64  `bson:"image_latency_ms,omitempty" json:"imageLatencyMs,omitempty"`
+	ImageError     string `bson:"image_error,omitempty" json:"imageError,omitempty"`
+
+	GeneratedAt time.Time `bson:"generated_at,omitempty" json:"generatedAt,omitempty"`
+}
+
+// PostVariant เนื้อหาทางเลือกของโพสต์สำหรับทำ A/B test
+type PostVariant struct {
+	Key         string `bson:"key" json:"key"`
+	Content     string `bson:"content" json:"content"`
+	Image       string `bson:"image,omitempty" json:"image,omitempty"`
+	Impressions int64  `bson:"impressions" json:"impressions"`
+	Clicks      int64  `bson:"clicks" json:"clicks"`
+	IsWinner    bool   `bson:"is_winner" json:"isWinner"`
 }
 
 // AutoConfig โครงสร้างการตั้งค่าระบบ AI Automation
 type AutoConfig struct {
     IsEnabled       bool     `json:"isEnabled" bson:"is_enabled"`
-    FrequencyPerDay int      `json:"frequencyPerDay" bson:"frequency_per_day"`
-    Topic           string   `json:"topic" bson:"topic"`
-    BasePrompt      string   `json:"basePrompt" bson:"base_prompt"`
-    Model           string   `json:"model" bson:"model"`
-    ScheduledTimes  []string `json:"scheduledTimes" bson:"scheduled_times"` // เพิ่มบรรทัดนี้
-}
\ No newline at end of file
+    FrequencyPerDay int      `json:"frequencyPerDay" bson:"frequency_per_day" binding:"min=0,max=24"`
+    Topic           string   `json:"topic" bson:"topic" binding:"max=200"`
+    BasePrompt      string   `json:"basePrompt" bson:"base_prompt" binding:"max=2000"`
+    Model           string   `json:"model" bson:"model" binding:"max=100"`
+    ScheduledTimes  []string `json:"scheduledTimes" bson:"scheduled_times" binding:"max=24,dive,datetime=15:04"` // เพิ่มบรรทัดนี้
+    // ความคล้าย (0-1) ที่ถือว่าซ้ำกับโพสต์ล่าสุด DuplicateLookback โพสต์
+    DuplicateThreshold float64 `json:"duplicateThreshold" bson:"duplicate_threshold" binding:"min=0,max=1"`
+    DuplicateLookback  int     `json:"duplicateLookback" bson:"duplicate_lookback" binding:"min=0,max=1000"`
+    // งบรายเดือน (USD) เกินแล้วหยุด automation, 0 = ไม่จำกัด
+    MonthlyBudgetUSD float64 `json:"monthlyBudgetUsd" bson:"monthly_budget_usd" binding:"min=0"`
+    // Watermark โลโก้/ข้อความที่ทับบนรูปที่ AI สร้าง
+    Watermark Watermark `json:"watermark" bson:"watermark"`
+}
+
+// Watermark ตั้งค่าการทับโลโก้ ค่า 0/ว่างจะใช้ค่าเริ่มต้น (ดู watermarkDefaults)
+type Watermark struct {
+	// Profiles โปรไฟล์ที่ใส่ watermark: automation = โพสต์อัตโนมัติ, editor = รูปที่ editor สั่งสร้าง ว่าง = ปิด
+	Profiles    []string `json:"profiles" bson:"profiles" binding:"max=2,dive,oneof=automation editor"`
+	LogoMediaID string   `json:"logoMediaId" bson:"logo_media_id" binding:"omitempty,mongodb"`
+	Position    string   `json:"position" bson:"position" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right center"`
+	Opacity     float64  `json:"opacity" bson:"opacity" binding:"min=0,max=1"`
+	// Margin ระยะห่างจากขอบของรูปต้นฉบับ (px)
+	Margin int `json:"margin" bson:"margin" binding:"min=0,max=500"`
+	// LogoScale ความกว้างโลโก้เทียบกับความกว้างรูป
+	LogoScale float64 `json:"logoScale" bson:"logo_scale" binding:"min=0,max=1"`
+	Caption   string  `json:"caption" bson:"caption" binding:"max=100"`
+}
+
+// UsageRecord การใช้งาน Gemini/Imagen ต่อการเรียก 1 ครั้ง
+type UsageRecord struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind            string             `bson:"kind" json:"kind"`
+	Model           string             `bson:"model" json:"model"`
+	Profile         string             `bson:"profile" json:"profile"`
+	User            string             `bson:"user" json:"user"`
+	PromptTokens    int64              `bson:"prompt_tokens" json:"promptTokens"`
+	CandidateTokens int64              `bson:"candidate_tokens" json:"candidateTokens"`
+	Images          int64              `bson:"images" json:"images"`
+	CostUSD         float64            `bson:"cost_usd" json:"costUsd"`
+	JobID           string             `bson:"job_id,omitempty" json:"jobId,omitempty"`
+	Day             string             `bson:"day" json:"day"`
+	CreatedAt       time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// UsageSummary ผลรวม usage ตามกลุ่ม (วัน/profile/user/model)
+type UsageSummary struct {
+	Key             string  `bson:"_id" json:"key"`
+	Calls           int64   `bson:"calls" json:"calls"`
+	PromptTokens    int64   `bson:"prompt_tokens" json:"promptTokens"`
+	CandidateTokens int64   `bson:"candidate_tokens" json:"candidateTokens"`
+	Images          int64   `bson:"images" json:"images"`
+	CostUSD         float64 `bson:"cost_usd" json:"costUsd"`
+}
+
+const (
+	CommentStatusPending  = "pending"
+	CommentStatusApproved = "approved"
+	CommentStatusHidden   = "hidden"
+)
+
+// Comment ความคิดเห็นของผู้เข้าชม (ParentID != nil คือคำตอบ)
+type Comment struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	PostID      primitive.ObjectID  `bson:"post_id" json:"postId"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parentId,omitempty"`
+	Depth       int                 `bson:"depth" json:"depth"`
+	Author      string              `bson:"author" json:"author"`
+	Body        string              `bson:"body" json:"body"`
+	Status      string              `bson:"status" json:"status"`
+	VisitorHash string              `bson:"visitor_hash" json:"-"`
+	CreatedAt   time.Time           `bson:"created_at" json:"createdAt"`
+	ModeratedBy string              `bson:"moderated_by,omitempty" json:"moderatedBy,omitempty"`
+	ModeratedAt *time.Time          `bson:"moderated_at,omitempty" json:"moderatedAt,omitempty"`
+}
+
+// PostRevision สำเนาเต็มของโพสต์หลังการเปลี่ยนแปลงแต่ละครั้ง
+type PostRevision struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"postId"`
+	Version   int64              `bson:"version" json:"version"`
+	Snapshot  Post               `bson:"snapshot" json:"snapshot"`
+	Actor     string             `bson:"actor" json:"actor"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
+}
+
+// Category หมวดหมู่ของโพสต์ จัดเป็นต้นไม้ด้วย ParentID (nil = หมวดบนสุด)
+type Category struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name        string              `bson:"name" json:"name"`
+	Slug        string              `bson:"slug" json:"slug"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parentId,omitempty"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"createdAt"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updatedAt"`
+}
+
+const (
+	JobStatusQueued  = "queued"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// BulkJob งานแก้ไขโพสต์หลายรายการที่ทำงานเบื้องหลัง พร้อมผลลัพธ์ของแต่ละโพสต์
+type BulkJob struct {
+	ID         primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Action     string               `bson:"action" json:"action"`
+	PostIDs    []primitive.ObjectID `bson:"post_ids" json:"postIds"`
+	AddTags    []string             `bson:"add_tags,omitempty" json:"addTags,omitempty"`
+	RemoveTags []string             `bson:"remove_tags,omitempty" json:"removeTags,omitempty"`
+	Status     string               `bson:"status" json:"status"`
+	Total      int                  `bson:"total" json:"total"`
+	Succeeded  int                  `bson:"succeeded" json:"succeeded"`
+	Failed     int                  `bson:"failed" json:"failed"`
+	Results    []BulkItemResult     `bson:"results" json:"results"`
+	CreatedBy  string               `bson:"created_by" json:"createdBy"`
+	CreatedAt  time.Time            `bson:"created_at" json:"createdAt"`
+	StartedAt  *time.Time           `bson:"started_at,omitempty" json:"startedAt,omitempty"`
+	FinishedAt *time.Time           `bson:"finished_at,omitempty" json:"finishedAt,omitempty"`
+	Error      string               `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// BulkItemResult ผลของโพสต์หนึ่งรายการใน BulkJob
+type BulkItemResult struct {
+	PostID primitive.ObjectID `bson:"post_id" json:"postId"`
+	OK     bool               `bson:"ok" json:"ok"`
+	Error  string             `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Media รูปที่เก็บในระบบ ไฟล์จริงอยู่ใน collection media_blobs แยกตาม Key
+type Media struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// PostID โพสต์ที่สร้างรูปนี้ ใช้ลบรูปตามเมื่อโพสต์ถูก purge
+	PostID   *primitive.ObjectID `bson:"post_id,omitempty" json:"postId,omitempty"`
+	Source   string              `bson:"source" json:"source"`
+	Mime     string              `bson:"mime" json:"mime"`
+	Width    int                 `bson:"width" json:"width"`
+	Height   int                 `bson:"height" json:"height"`
+	Size     int                 `bson:"size" json:"size"`
+	Blurhash string              `bson:"blurhash" json:"blurhash"`
+	Variants []MediaVariant      `bson:"variants" json:"variants"`
+	// ข้อมูลสำหรับคลังรูป (media library)
+	Filename string `bson:"filename,omitempty" json:"filename,omitempty"`
+	// Alt/Caption เป็นภาษาไทย ส่วน *EN เป็นภาษาอังกฤษ
+	Alt       string `bson:"alt,omitempty" json:"alt,omitempty"`
+	AltEN     string `bson:"alt_en,omitempty" json:"altEn,omitempty"`
+	Caption   string `bson:"caption,omitempty" json:"caption,omitempty"`
+	CaptionEN string `bson:"caption_en,omitempty" json:"captionEn,omitempty"`
+	// AltSource ผู้เขียนคำอธิบายล่าสุด: "ai:<provider>" หรือ "editor"
+	AltSource  string     `bson:"alt_source,omitempty" json:"altSource,omitempty"`
+	Tags       []string   `bson:"tags,omitempty" json:"tags,omitempty"`
+	UploadedBy string     `bson:"uploaded_by,omitempty" json:"uploadedBy,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"createdAt"`
+	UpdatedAt  *time.Time `bson:"updated_at,omitempty" json:"updatedAt,omitempty"`
+}
+
+// MediaVariant ไฟล์หนึ่งไฟล์ของรูป เช่น original.png หรือ w640.jpg
+type MediaVariant struct {
+	Key    string `bson:"key" json:"key"`
+	Mime   string `bson:"mime" json:"mime"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	Size   int    `bson:"size" json:"size"`
+}
+
+// ImageSet ข้อมูลรูปที่ฝังไว้ในโพสต์ พร้อมใช้กับ <img srcset>
+type ImageSet struct {
+	MediaID   primitive.ObjectID `bson:"media_id" json:"mediaId"`
+	Src       string             `bson:"src" json:"src"`
+	Srcset    string             `bson:"srcset" json:"srcset"`
+	Width     int                `bson:"width" json:"width"`
+	Height    int                `bson:"height" json:"height"`
+	Blurhash  string             `bson:"blurhash" json:"blurhash"`
+	Alt       string             `bson:"alt,omitempty" json:"alt,omitempty"`
+	AltEN     string             `bson:"alt_en,omitempty" json:"altEn,omitempty"`
+	Caption   string             `bson:"caption,omitempty" json:"caption,omitempty"`
+	CaptionEN string             `bson:"caption_en,omitempty" json:"captionEn,omitempty"`
+	Sources   []ImageSource      `bson:"sources" json:"sources"`
+	// Stored media นี้เพิ่งถูกสร้างจาก data URL ใน request นี้ (ไม่บันทึกลง DB)
+	Stored bool `bson:"-" json:"-"`
+}
+
+type ImageSource struct {
+	URL    string `bson:"url" json:"url"`
+	Mime   string `bson:"mime" json:"mime"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+	Size   int    `bson:"size" json:"size"`
+}
@@ -57,20 +57,3 @@ func (s *AutomationService) GenerateAndPost() {
 	s.postRepo.Create(ctx, newPost)
 	log.Println("✨ AI Content Posted Successfully")
 }
-
-func initAI() {
-    key := os.Getenv("GEMINI_API_KEY")
-    if key == "" { return }
-
-    ctx := context.Background()
-    
-    // ตัด option.WithEndpoint ออก เพื่อให้ SDK เลือก path ที่ถูกต้องเอง
-    client, err := genai.NewClient(ctx, option.WithAPIKey(key))
-    
-    if err != nil {
-        log.Printf("❌ สร้าง Gemini Client ล้มเหลว: %v", err)
-        return
-    }
-    geminiClient = client
-    log.Println("✅ Gemini AI พร้อมใช้งานแล้ว")
-}
\ No newline at end of file